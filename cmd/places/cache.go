@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+// dumpEntry is a line of cache dump.
+type dumpEntry struct {
+	Params   search.Params `json:"params"`
+	Places   []dumpPlace   `json:"places"`
+	CachedAt time.Time     `json:"cached_at"`
+}
+
+// dumpPlace mirrors place.Model, but keeps fields
+// which are hidden from API clients.
+type dumpPlace struct {
+	Slug        string `json:"slug"`
+	SubTitle    string `json:"subtitle"`
+	Title       string `json:"title"`
+	Type        string `json:"type,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	Weight      int    `json:"weight,omitempty"`
+}
+
+// cacheCommand dumps cached entries to stdout or
+// restores them from stdin, one JSON entry per line.
+func cacheCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected dump or restore")
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("cache "+action, flag.ExitOnError)
+	var (
+		tenantsPath = fs.String("tenants", "", "path to JSON file with tenants")
+		tid         = fs.String("tenant", "", "id of tenant which cache is dumped or restored, empty uses cache of the default tenant")
+	)
+	var sf storageFlags
+	sf.register(fs)
+	fs.Parse(args)
+
+	if action != "dump" && action != "restore" {
+		return errors.Errorf("unknown cache command: %s", action)
+	}
+
+	registry, err := loadTenants(*tenantsPath)
+	if err != nil {
+		return err
+	}
+	ctx, err := withTenant(context.Background(), registry, *tid)
+	if err != nil {
+		return err
+	}
+
+	s, err := sf.open()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	switch action {
+	case "dump":
+		return dumpCache(ctx, s, os.Stdout)
+	default:
+		return restoreCache(ctx, s, os.Stdin)
+	}
+}
+
+func dumpCache(ctx context.Context, repository search.Repository, out io.Writer) error {
+	entries, err := repository.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list entries")
+	}
+
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		d := dumpEntry{
+			Params:   e.Params,
+			Places:   make([]dumpPlace, len(e.Places)),
+			CachedAt: e.CachedAt,
+		}
+		for i, p := range e.Places {
+			d.Places[i] = dumpPlace(p)
+		}
+		if err := enc.Encode(&d); err != nil {
+			return errors.Wrap(err, "encode entry")
+		}
+	}
+
+	log.Info("cache dumped", map[string]interface{}{
+		"entries": len(entries),
+	})
+	return errors.Wrap(w.Flush(), "flush")
+}
+
+// restoreCache caches entries read from in. Restored
+// entries are cached as fresh ones.
+func restoreCache(ctx context.Context, repository search.Repository, in io.Reader) error {
+	dec := json.NewDecoder(in)
+	var restored int
+	for {
+		var d dumpEntry
+		if err := dec.Decode(&d); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrapf(err, "decode entry %d", restored+1)
+		}
+
+		places := make([]place.Model, len(d.Places))
+		for i, p := range d.Places {
+			places[i] = place.Model(p)
+		}
+		if err := repository.Cache(ctx, d.Params, places); err != nil {
+			return errors.Wrap(err, "cache entry")
+		}
+		if err := repository.IndexPlaces(ctx, d.Params.Locale, places); err != nil {
+			return errors.Wrap(err, "index places")
+		}
+		restored++
+	}
+
+	log.Info("cache restored", map[string]interface{}{
+		"entries": restored,
+	})
+	return nil
+}
@@ -1,193 +1,343 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 	"time"
 
 	_ "net/http/pprof"
 
-	"github.com/go-redis/redis"
-	"github.com/heptiolabs/healthcheck"
 	"github.com/pkg/errors"
-	"go.opencensus.io/exporter/jaeger"
-	"go.opencensus.io/exporter/prometheus"
-	"go.opencensus.io/plugin/ochttp"
-	"go.opencensus.io/stats/view"
-	"go.opencensus.io/trace"
 
 	httpBroker "github.com/romanyx/places/internal/broker/http"
 	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/requester/dataset"
 	httpRequester "github.com/romanyx/places/internal/requester/http"
 	"github.com/romanyx/places/internal/search"
-	redisRepository "github.com/romanyx/places/internal/storage/redis"
+	"github.com/romanyx/places/internal/tenant"
 )
 
 const (
-	timeout            = 3 * time.Second
-	redisCheckInterval = 15 * time.Second
+	timeout              = 3 * time.Second
+	storageCheckInterval = 15 * time.Second
+	storageCheckTimeout  = time.Second
+	cooldownRefresh      = time.Second
+	limitBackoff         = 0.9
 )
 
+const usage = `Usage: places <command> [flags]
+
+Commands:
+  serve         run API server, default when command is omitted
+  search        search places and print them
+  cache dump    write cached entries to stdout as NDJSON
+  cache restore read NDJSON entries from stdin and cache them
+  warm          fill cache with places of terms read from stdin
+
+Run 'places <command> -h' for command flags.
+`
+
+// commands maps subcommand names to their runners.
+var commands = map[string]func(args []string) error{
+	"serve":  serveCommand,
+	"search": searchCommand,
+	"cache":  cacheCommand,
+	"warm":   warmCommand,
+}
+
 func main() {
-	var (
-		addr        = flag.String("addr", ":8080", "address of http server")
-		redisURL    = flag.String("redis", "127.0.0.1:6379", "redis database URL")
-		debugAddr   = flag.String("debug", ":1234", "debug server addr")
-		healthAddr  = flag.String("health", ":8081", "health check addr")
-		metricsAddr = flag.String("metrics", ":8082", "metrics server addr")
-		jaegerURL   = flag.String("jaeger", "http://127.0.0.1:14268", "jaeger server url")
-		logLevel    = flag.String("log-level", "debug", "log level")
-	)
-	flag.Parse()
-	log.SetLevel(*logLevel)
-
-	// Health checker handler.
-	health := healthcheck.NewHandler()
-	// Make a channel for errors.
-	errChan := make(chan error)
-
-	// Prepare prometheus metrics.
-	log.Info("register exporter", nil)
-	pex, err := prometheus.NewExporter(prometheus.Options{})
-	if err != nil {
-		log.Fatal(errors.Wrap(err, "register exporter"), nil)
-	}
-	view.RegisterExporter(pex)
-	if err := view.Register(
-		ochttp.DefaultServerViews...,
-	); err != nil {
-		log.Fatal(errors.Wrap(err, "failed to register views"), nil)
-	}
-
-	// Build and start metrics server.
-	mux := http.NewServeMux()
-	mux.Handle("/metrics", pex)
-	metricsServer := http.Server{
-		Addr:    *metricsAddr,
-		Handler: mux,
-	}
-	log.Info("starting metrics server", map[string]interface{}{
-		"addr": *metricsAddr,
-	})
-	go func() {
-		if err := metricsServer.ListenAndServe(); err != nil {
-			errChan <- errors.Wrap(err, "metrics server")
-		}
-	}()
-
-	// Register trace exporter.
-	log.Info("register jaeger exporter", map[string]interface{}{
-		"addr": *jaegerURL,
-	})
-	jexp, err := jaeger.NewExporter(jaeger.Options{
-		CollectorEndpoint: fmt.Sprintf("%s/api/traces", *jaegerURL),
-		ServiceName:       "places",
-	})
-	if err != nil {
-		log.Fatal(errors.Wrapf(err, "failed to create jaeger exporter"), nil)
-	}
-	defer jexp.Flush()
-	trace.RegisterExporter(jexp)
-	trace.ApplyConfig(trace.Config{
-		DefaultSampler: trace.ProbabilitySampler(0.1),
-	})
-
-	// Redis connection.
-	log.Info("connectng to redis", map[string]interface{}{
-		"addr": *redisURL,
-	})
-	redis := redis.NewClient(&redis.Options{
-		Addr: *redisURL,
-	})
-
-	// Add redis health check.
-	redisPing := healthcheck.Check(func() error {
-		if _, err := redis.Ping().Result(); err == nil {
-			return errors.Wrap(err, "ping")
-		}
+	name, args := "serve", os.Args[1:]
+	// Flags without command are flags of serve
+	// to keep old invocations working.
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-		return nil
-	})
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := command(args); err != nil {
+		log.Fatal(errors.Wrap(err, name), nil)
+	}
+}
+
+// serviceFlags configure search service, they are
+// shared by commands which perform searches.
+type serviceFlags struct {
+	storageFlags
+	negativeTTL time.Duration
+	datasetPath string
+	localeChain string
+	rankPrefix  float64
+	rankPopular float64
+	homeMarket  string
+	rankMarket  float64
+	cooldown    search.CooldownConfig
+	maxBody     int64
+	maxPlaces   int
+	plainText   bool
+	dedupe      bool
+	normalize   bool
+	types       string
+	maxResults  int
+	rewrite     bool
+	maxEdits    int
+	tenantsPath string
+}
 
-	health.AddReadinessCheck("redis ready", redisPing)
-	health.AddLivenessCheck("redis live", healthcheck.Async(redisPing, redisCheckInterval))
+func (f *serviceFlags) register(fs *flag.FlagSet) {
+	f.storageFlags.register(fs)
+	fs.DurationVar(&f.negativeTTL, "negative-ttl", time.Minute, "ttl of empty and bad request results cache, 0 disables it")
+	fs.StringVar(&f.datasetPath, "dataset", "", "path to CSV or JSON dataset used when aviasales is down and cache not found")
+	fs.StringVar(&f.localeChain, "locale-fallback", "uk:ru,be:ru,kk:ru,ru:en", "comma separated locale fallbacks in form locale:fallback")
+	fs.Float64Var(&f.rankPrefix, "rank-prefix", 10, "ranking boost of places which match the term prefix")
+	fs.Float64Var(&f.rankPopular, "rank-popularity", 1, "ranking factor of places popularity")
+	fs.StringVar(&f.homeMarket, "home-market", "", "country code of the home market boosted in ranking")
+	fs.Float64Var(&f.rankMarket, "rank-market", 5, "ranking boost of places in home market")
+	fs.DurationVar(&f.cooldown.Default, "cooldown", 30*time.Second, "pause of upstream requests after rate limited response without Retry-After")
+	fs.DurationVar(&f.cooldown.Max, "cooldown-max", 10*time.Minute, "maximal pause of upstream requests after rate limited response, 0 disables cooldown")
+	f.cooldown.Refresh = cooldownRefresh
+	fs.BoolVar(&f.dedupe, "dedupe", true, "drop places with duplicate slug and type")
+	fs.BoolVar(&f.normalize, "normalize", true, "trim titles and normalize their Unicode form")
+	fs.StringVar(&f.types, "supported-types", "", "comma separated place types kept in results, empty keeps all")
+	fs.BoolVar(&f.rewrite, "rewrite", false, "retry terms which gave no places transliterated or corrected with dataset or cached names")
+	fs.IntVar(&f.maxEdits, "rewrite-distance", 2, "maximum edit distance between term and name it is corrected to")
+	fs.StringVar(&f.tenantsPath, "tenants", "", "path to JSON file with tenants, empty serves the default tenant only")
+	fs.IntVar(&f.maxResults, "max-results", 0, "maximum number of places in result, 0 disables it")
+	fs.Int64Var(&f.maxBody, "upstream-max-body", 2<<20, "maximum size of aviasales response body in bytes")
+	fs.IntVar(&f.maxPlaces, "upstream-max-places", 100, "maximum number of places read from aviasales response")
+	fs.BoolVar(&f.plainText, "upstream-plain-text", false, "accept aviasales responses served as text/plain, only JSON is accepted otherwise")
+}
 
-	// Build and start health server.
-	healthServer := http.Server{
-		Addr:    *healthAddr,
-		Handler: health,
+// pipeline returns steps which process requested places.
+func (f *serviceFlags) pipeline() search.Pipeline {
+	var pipeline search.Pipeline
+	if f.normalize {
+		pipeline = append(pipeline, search.NormalizeStep())
+	}
+	if f.types != "" {
+		pipeline = append(pipeline, search.TypesStep(splitList(f.types)...))
 	}
+	if f.dedupe {
+		pipeline = append(pipeline, search.DedupeStep())
+	}
+	return pipeline
+}
 
-	log.Info("starting health server", map[string]interface{}{
-		"addr": *healthAddr,
-	})
-	go func() {
-		if err := healthServer.ListenAndServe(); err != nil {
-			errChan <- errors.Wrap(err, "health server")
-		}
-	}()
+// registry loads tenants when they are configured.
+func (f *serviceFlags) registry() (*tenant.Registry, error) {
+	return loadTenants(f.tenantsPath)
+}
 
-	client := http.Client{}
-	// Start API server.
-	server := setupServer(*addr, &client, redis)
+// loadTenants loads tenants from path, empty path
+// gives no registry.
+func loadTenants(path string) (*tenant.Registry, error) {
+	if path == "" {
+		return nil, nil
+	}
+	registry, err := tenant.Load(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "load tenants")
+	}
+	return registry, nil
+}
 
-	go func() {
-		log.Info("startng server", map[string]interface{}{
-			"addr": server.Addr,
-		})
-		if err := server.ListenAndServe(); err != nil {
-			errChan <- errors.Wrap(err, "failed to serve grpc")
-		}
-	}()
+// withTenant returns ctx of the tenant with a given
+// id, empty id keeps the default tenant.
+func withTenant(ctx context.Context, registry *tenant.Registry, id string) (context.Context, error) {
+	if id == "" {
+		return ctx, nil
+	}
+	if registry == nil {
+		return nil, errors.New("tenant requires tenants file")
+	}
+	t, ok := registry.ByID(id)
+	if !ok {
+		return nil, errors.Errorf("unknown tenant: %s", id)
+	}
+	return tenant.NewContext(ctx, t), nil
+}
+
+// requesterOptions returns options of aviasales requester.
+func (f *serviceFlags) requesterOptions() []httpRequester.Option {
+	opts := []httpRequester.Option{
+		httpRequester.WithMaxBody(f.maxBody),
+		httpRequester.WithMaxPlaces(f.maxPlaces),
+	}
+	if f.plainText {
+		opts = append(opts, httpRequester.WithPlainText())
+	}
+	return opts
+}
+
+// options returns search options, dataset is returned
+// when it is configured so it can be watched.
+func (f *serviceFlags) options() ([]search.Option, *dataset.Requester, error) {
+	locales, err := search.ParseLocales(f.localeChain)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parse locale fallbacks")
+	}
+
+	opts := []search.Option{
+		search.WithLocales(locales),
+		search.WithRanker(search.ScoreRanker{
+			search.PrefixScorer(f.rankPrefix),
+			search.PopularityScorer(f.rankPopular),
+			search.MarketScorer(f.homeMarket, f.rankMarket),
+		}),
+	}
+	if f.negativeTTL > 0 {
+		opts = append(opts, search.WithNegativeTTL(f.negativeTTL))
+	}
+	opts = append(opts, search.WithPipeline(f.pipeline()), search.WithMaxResults(f.maxResults))
 
-	// Start debug server.
-	debugServer := setupDebugServer(*debugAddr)
-	go func() {
-		log.Info("startng debug server", map[string]interface{}{
-			"addr": debugServer.Addr,
+	var ds *dataset.Requester
+	if f.datasetPath != "" {
+		log.Info("loading dataset", map[string]interface{}{
+			"path": f.datasetPath,
 		})
-		if err := debugServer.ListenAndServe(); err != nil {
-			errChan <- errors.Wrap(err, "debug server")
+		ds, err = dataset.New(f.datasetPath)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "load dataset")
+		}
+		opts = append(opts, search.WithFallback(ds))
+	}
+	if f.rewrite {
+		rewriter := search.ChainRewriter{search.TranslitRewriter{}}
+		if ds != nil {
+			rewriter = append(rewriter, search.FuzzyRewriter{Dictionary: ds, MaxDistance: f.maxEdits})
 		}
-	}()
-	defer debugServer.Close()
-
-	// Make a channel to listen for an interrupt or terminate signal from the OS.
-	// Use a buffered channel because the signal package requires it.
-	osSignals := make(chan os.Signal, 1)
-	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
-
-	select {
-	case err := <-errChan:
-		log.Fatal(errors.Wrap(err, "critical error"), nil)
-	case <-osSignals:
-		log.Info("stop by signal", nil)
-		if err := server.Close(); err != nil {
-			log.Fatal(errors.Wrap(err, "failed to stop server"), nil)
+		opts = append(opts, search.WithRewriter(rewriter))
+		if ds == nil {
+			// Without dataset terms are corrected with names
+			// of cached places.
+			opts = append(opts, search.WithIndexRewriter(f.maxEdits))
 		}
 	}
+
+	return opts, ds, nil
 }
 
-func setupServer(addr string, client *http.Client, redis *redis.Client) *http.Server {
-	var requester search.Requester
-	requester = httpRequester.New(client)
-	requester = search.NewRequesterWithTrace(requester)
+// exposedHeaders are response headers available
+// to cross-origin clients.
+var exposedHeaders = []string{
+	"Age",
+	"ETag",
+	"Retry-After",
+	"Warning",
+	"X-Places-Degraded",
+	"X-Places-Did-You-Mean",
+	"X-Request-ID",
+	"X-Total-Count",
+}
 
-	var repository search.Repository
-	repository = redisRepository.NewRepository(redis)
-	repository = search.NewRepositoryWithTrace(repository)
+// config holds options of the API server components.
+type config struct {
+	limit    search.LimitConfig
+	cooldown search.CooldownConfig
+	// cooldownStore shares cooldown between
+	// replicas, it is optional.
+	cooldownStore search.Cooldown
+	searchOpts    []search.Option
+	requesterOpts []httpRequester.Option
+	serverOpts    []httpBroker.Option
+	// tenants get own requesters, so they do
+	// not share limits, it is optional.
+	tenants *tenant.Registry
+}
+
+func setupServer(addr string, client *http.Client, repository search.Repository, cfg config) (*http.Server, httpBroker.Admin) {
+	service, err := setupService(client, repository, cfg)
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "setup service"), nil)
+	}
 
 	var searcher httpBroker.Searcher
-	searcher = search.NewService(requester, repository, timeout)
+	searcher = service
 	searcher = httpBroker.NewSearcherWithTrace(searcher)
 	searcher = httpBroker.NewSearcherWithLog(searcher)
 
-	server := httpBroker.NewServer(addr, searcher)
-	return server
+	server := httpBroker.NewServer(addr, searcher, cfg.serverOpts...)
+	return server, service
+}
+
+func setupService(client *http.Client, repository search.Repository, cfg config) (*search.Service, error) {
+	requester, err := setupRequester(client, cfg.limit, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.tenants != nil {
+		tenants := make(map[string]search.Requester)
+		for _, t := range cfg.tenants.Tenants() {
+			tenants[t.ID], err = setupRequester(client, tenantLimit(cfg.limit, t.Limit), cfg)
+			if err != nil {
+				return nil, errors.Wrapf(err, "tenant %s", t.ID)
+			}
+		}
+		requester = search.NewRequesterPerTenant(requester, tenants)
+	}
+	if cfg.cooldown.Max > 0 {
+		requester = search.NewRequesterWithCooldown(requester, cfg.cooldownStore, cfg.cooldown)
+	}
+	requester = search.NewRequesterWithTrace(requester)
+
+	repository = search.NewRepositoryWithTrace(repository)
+
+	return search.NewService(requester, repository, timeout, cfg.searchOpts...), nil
+}
+
+// setupRequester returns aviasales requester with limit.
+func setupRequester(client *http.Client, limit search.LimitConfig, cfg config) (search.Requester, error) {
+	var requester search.Requester
+	requester = httpRequester.New(client, cfg.requesterOpts...)
+	if limit.Max > 0 {
+		return search.NewRequesterWithLimit(requester, limit)
+	}
+	return requester, nil
+}
+
+// tenantLimit returns limit config with maximum of the
+// tenant, zero maximum keeps the default config.
+func tenantLimit(limit search.LimitConfig, max int) search.LimitConfig {
+	if max <= 0 {
+		return limit
+	}
+	limit.Max = max
+	if limit.Min > max {
+		limit.Min = max
+	}
+	if limit.Initial > max {
+		limit.Initial = max
+	}
+	return limit
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// parseCIDRs parses comma separated list of CIDRs.
+func parseCIDRs(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, cidr := range splitList(s) {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse %s", cidr)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
 }
 
 func setupDebugServer(addr string) *http.Server {
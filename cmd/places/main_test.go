@@ -12,11 +12,12 @@ import (
 	"os"
 	"testing"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/ory/dockertest"
 
 	"github.com/romanyx/places/internal/docker"
 	logPkg "github.com/romanyx/places/internal/log"
+	redisRepository "github.com/romanyx/places/internal/storage/redis"
 )
 
 var (
@@ -73,6 +74,6 @@ func prepareServer(h http.Handler) *http.Server {
 		},
 	}
 
-	server := setupServer("", &client, redisClient)
+	server, _ := setupServer("", &client, redisRepository.NewRepository(redisClient), config{})
 	return server
 }
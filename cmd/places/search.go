@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/search"
+)
+
+// searchCommand searches places with the same service
+// API server uses and prints them as a table.
+func searchCommand(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	var (
+		term   = fs.String("term", "", "search term")
+		locale = fs.String("locale", "en", "locale of places")
+		types  = fs.String("types", "", "comma separated types of places")
+		tid    = fs.String("tenant", "", "id of tenant to search for, empty searches for the default tenant")
+	)
+	var sf serviceFlags
+	sf.register(fs)
+	fs.Parse(args)
+
+	if *term == "" {
+		return errors.New("term is required")
+	}
+
+	opts, _, err := sf.options()
+	if err != nil {
+		return err
+	}
+	s, err := sf.open()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+	cfg := config{
+		searchOpts:    opts,
+		cooldown:      sf.cooldown,
+		cooldownStore: s.cooldown,
+		requesterOpts: sf.requesterOptions(),
+	}
+	if cfg.tenants, err = sf.registry(); err != nil {
+		return err
+	}
+	service, err := setupService(&http.Client{}, s, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := withTenant(context.Background(), cfg.tenants, *tid)
+	if err != nil {
+		return err
+	}
+	ctx, meta := search.WithMeta(ctx)
+	places, err := service.Search(ctx, search.Params{
+		Term:   *term,
+		Locale: *locale,
+		Types:  splitList(*types),
+	})
+	if err != nil {
+		return errors.Wrap(err, "search")
+	}
+
+	log.Info("places found", map[string]interface{}{
+		"count":     len(places),
+		"cached":    meta.Cached,
+		"stale":     meta.Stale,
+		"degraded":  meta.Degraded,
+		"corrected": meta.Corrected,
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SLUG\tTITLE\tSUBTITLE\tTYPE\tCOUNTRY")
+	for _, p := range places {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Slug, p.Title, p.SubTitle, p.Type, p.CountryCode)
+	}
+	return w.Flush()
+}
@@ -24,6 +24,7 @@ func TestSearch(t *testing.T) {
 
 func getPlaces200(t *testing.T) {
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprint(w, okResponse)
 	})
 	server := prepareServer(h)
@@ -80,7 +81,7 @@ func getPlaces200Cache(t *testing.T) {
 			Title:    "Moscow",
 		},
 	})
-	defer redisClient.FlushDB()
+	defer redisClient.FlushDB(context.Background())
 
 	t.Log("Given the need to fetch an list of places when request failed and have cache")
 	{
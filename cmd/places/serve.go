@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/heptiolabs/healthcheck"
+	"github.com/pkg/errors"
+	"go.opencensus.io/exporter/jaeger"
+	"go.opencensus.io/exporter/prometheus"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+
+	httpBroker "github.com/romanyx/places/internal/broker/http"
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/recovery"
+	httpRequester "github.com/romanyx/places/internal/requester/http"
+	"github.com/romanyx/places/internal/search"
+)
+
+// serveCommand runs API server and its companion
+// servers until signal is received.
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		addr        = fs.String("addr", ":8080", "address of http server")
+		debugAddr   = fs.String("debug", ":1234", "debug server addr")
+		healthAddr  = fs.String("health", ":8081", "health check addr")
+		metricsAddr = fs.String("metrics", ":8082", "metrics server addr")
+		jaegerURL   = fs.String("jaeger", "http://127.0.0.1:14268", "jaeger server url")
+		logLevel    = fs.String("log-level", "debug", "log level")
+		accessLog   = fs.Bool("access-log", true, "write access log to stdout")
+		accessRate  = fs.Float64("access-log-sample", 1, "fraction of requests written to access log")
+		accessSkip  = fs.String("access-log-exclude", "", "comma separated paths excluded from access log")
+		limitInit   = fs.Int("limit-initial", 20, "initial concurrency limit of upstream requests")
+		limitMin    = fs.Int("limit-min", 1, "minimal concurrency limit of upstream requests, at least 1")
+		limitMax    = fs.Int("limit-max", 200, "maximal concurrency limit of upstream requests, 0 disables limiter")
+		limitTerm   = fs.Int("limit-per-term", 4, "concurrency limit of upstream requests with the same params, 0 disables it")
+		datasetPoll = fs.Duration("dataset-reload", 30*time.Second, "interval of dataset file change checks")
+		maxAge      = fs.Duration("max-age", 5*time.Minute, "time during which clients may cache responses")
+		corsOrigins = fs.String("cors-origins", "", "comma separated origins allowed for cross-origin requests, * allows any, empty disables CORS")
+		corsMethods = fs.String("cors-methods", "GET,POST", "comma separated methods allowed for cross-origin requests")
+		corsHeaders = fs.String("cors-headers", "Content-Type,Accept-Language,If-None-Match", "comma separated headers allowed for cross-origin requests")
+		corsMaxAge  = fs.Duration("cors-max-age", 10*time.Minute, "time during which browsers may cache preflight responses")
+		tenantHdr   = fs.String("tenant-header", "X-Places-Tenant", "request header which names tenant, honored from trusted proxies and on admin server, tenant is resolved by host without it")
+		debounce    = fs.Duration("typeahead-debounce", 150*time.Millisecond, "pause after the last term sent to type-ahead endpoint before search starts")
+		requestID   = fs.String("request-id-header", "X-Request-ID", "header of request id taken from clients and returned in responses")
+		proxies     = fs.String("trusted-proxies", "", "comma separated CIDRs of proxies trusted to pass client address in X-Forwarded-For and tenant header")
+		maxBody     = fs.Int64("max-body", 1<<20, "maximum size of request body in bytes")
+		secHeaders  = fs.Bool("security-headers", true, "set security headers of responses")
+		tlsCert     = fs.String("tls-cert", "", "certificate file of the server, enables TLS with -tls-key, reloaded on change")
+		tlsKey      = fs.String("tls-key", "", "key file of the server certificate, reloaded on change")
+		tlsClientCA = fs.String("tls-client-ca", "", "CA file used to verify client certificates, empty disables client verification")
+		tlsRequire  = fs.Bool("tls-client-required", false, "reject clients without certificate, requires -tls-client-ca")
+		tlsMin      = fs.String("tls-min-version", "1.2", "minimum TLS version, one of 1.0, 1.1, 1.2 and 1.3")
+		tlsCiphers  = fs.String("tls-ciphers", "", "comma separated cipher suites allowed for TLS 1.2 and lower, one of TLS_ECDHE_*_WITH_AES_128_GCM_SHA256 is required by HTTP/2, empty allows default ones")
+		h2cEnabled  = fs.Bool("h2c", false, "serve HTTP/2 without TLS")
+		jsonp       = fs.Bool("jsonp", false, "enable JSONP callback query param")
+		adminAddr   = fs.String("admin", ":8083", "admin server addr")
+		adminToken  = fs.String("admin-token", "", "bearer token of admin server, empty disables admin server")
+	)
+	var sf serviceFlags
+	sf.register(fs)
+	fs.Parse(args)
+	log.SetLevel(*logLevel)
+
+	// Health checker handler.
+	health := healthcheck.NewHandler()
+	// Make a channel for errors.
+	errChan := make(chan error)
+
+	// Prepare prometheus metrics.
+	log.Info("register exporter", nil)
+	pex, err := prometheus.NewExporter(prometheus.Options{})
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "register exporter"), nil)
+	}
+	view.RegisterExporter(pex)
+	views := append([]*view.View{}, ochttp.DefaultServerViews...)
+	views = append(views, search.LimiterViews...)
+	views = append(views, search.CooldownViews...)
+	views = append(views, httpRequester.Views...)
+	views = append(views, httpBroker.TenantViews...)
+	views = append(views, recovery.Views...)
+	if err := view.Register(views...); err != nil {
+		log.Fatal(errors.Wrap(err, "failed to register views"), nil)
+	}
+
+	// Build and start metrics server.
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", pex)
+	metricsServer := http.Server{
+		Addr:    *metricsAddr,
+		Handler: mux,
+	}
+	log.Info("starting metrics server", map[string]interface{}{
+		"addr": *metricsAddr,
+	})
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil {
+			errChan <- errors.Wrap(err, "metrics server")
+		}
+	}()
+
+	// Register trace exporter.
+	log.Info("register jaeger exporter", map[string]interface{}{
+		"addr": *jaegerURL,
+	})
+	jexp, err := jaeger.NewExporter(jaeger.Options{
+		CollectorEndpoint: fmt.Sprintf("%s/api/traces", *jaegerURL),
+		ServiceName:       "places",
+	})
+	if err != nil {
+		log.Fatal(errors.Wrapf(err, "failed to create jaeger exporter"), nil)
+	}
+	defer jexp.Flush()
+	trace.RegisterExporter(jexp)
+	trace.ApplyConfig(trace.Config{
+		DefaultSampler: trace.ProbabilitySampler(0.1),
+	})
+
+	// Storage connection.
+	backend, err := sf.open()
+	if err != nil {
+		log.Fatal(err, nil)
+	}
+	defer backend.close()
+
+	// Add storage health check.
+	if backend.ping != nil {
+		storagePing := healthcheck.Check(backend.ping)
+		health.AddReadinessCheck(sf.backend+" ready", storagePing)
+		health.AddLivenessCheck(sf.backend+" live", healthcheck.Async(storagePing, storageCheckInterval))
+	}
+
+	// Build and start health server.
+	healthServer := http.Server{
+		Addr:    *healthAddr,
+		Handler: health,
+	}
+
+	log.Info("starting health server", map[string]interface{}{
+		"addr": *healthAddr,
+	})
+	go func() {
+		if err := healthServer.ListenAndServe(); err != nil {
+			errChan <- errors.Wrap(err, "health server")
+		}
+	}()
+
+	cfg := config{
+		serverOpts: []httpBroker.Option{
+			httpBroker.WithMaxAge(*maxAge),
+			httpBroker.WithDebounce(*debounce),
+		},
+		limit: search.LimitConfig{
+			Initial: *limitInit,
+			Min:     *limitMin,
+			Max:     *limitMax,
+			Backoff: limitBackoff,
+			PerTerm: *limitTerm,
+		},
+	}
+	searchOpts, ds, err := sf.options()
+	if err != nil {
+		log.Fatal(err, nil)
+	}
+	cfg.searchOpts = searchOpts
+	cfg.cooldown = sf.cooldown
+	cfg.cooldownStore = backend.cooldown
+	cfg.requesterOpts = sf.requesterOptions()
+	cfg.tenants, err = sf.registry()
+	if err != nil {
+		log.Fatal(err, nil)
+	}
+	trusted, err := parseCIDRs(*proxies)
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "parse trusted proxies"), nil)
+	}
+	if cfg.tenants != nil {
+		cfg.serverOpts = append(cfg.serverOpts, httpBroker.WithTenants(cfg.tenants, *tenantHdr, trusted))
+	}
+	middleware := []httpBroker.Middleware{
+		httpBroker.RequestID(*requestID),
+		httpBroker.RealIP(trusted),
+		httpBroker.BodyLimit(*maxBody),
+	}
+	if *secHeaders {
+		middleware = append(middleware, httpBroker.SecurityHeaders())
+	}
+	cfg.serverOpts = append(cfg.serverOpts, httpBroker.WithMiddleware(middleware...))
+	if ds != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go ds.Watch(ctx, *datasetPoll)
+	}
+	if *corsOrigins != "" {
+		cfg.serverOpts = append(cfg.serverOpts, httpBroker.WithCORS(httpBroker.CORS{
+			AllowedOrigins: splitList(*corsOrigins),
+			AllowedMethods: splitList(*corsMethods),
+			AllowedHeaders: splitList(*corsHeaders),
+			ExposedHeaders: exposedHeaders,
+			MaxAge:         *corsMaxAge,
+		}))
+	}
+	if *jsonp {
+		cfg.serverOpts = append(cfg.serverOpts, httpBroker.WithJSONP())
+	}
+	if *tlsRequire && *tlsClientCA == "" {
+		log.Fatal(errors.New("-tls-client-required requires -tls-client-ca"), nil)
+	}
+	if *tlsCert != "" || *tlsKey != "" {
+		tlsConfig, err := httpBroker.NewTLSConfig(httpBroker.TLS{
+			CertFile:          *tlsCert,
+			KeyFile:           *tlsKey,
+			ClientCAFile:      *tlsClientCA,
+			RequireClientCert: *tlsRequire,
+			MinVersion:        *tlsMin,
+			CipherSuites:      splitList(*tlsCiphers),
+		})
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "tls config"), nil)
+		}
+		cfg.serverOpts = append(cfg.serverOpts, httpBroker.WithTLSConfig(tlsConfig))
+	}
+	if *h2cEnabled {
+		cfg.serverOpts = append(cfg.serverOpts, httpBroker.WithH2C())
+	}
+	if *accessLog {
+		cfg.serverOpts = append(cfg.serverOpts, httpBroker.WithAccessLog(httpBroker.AccessLog{
+			Output:     os.Stdout,
+			SampleRate: *accessRate,
+			Exclude:    splitList(*accessSkip),
+		}))
+	}
+
+	client := http.Client{}
+	// Start API server.
+	server, admin := setupServer(*addr, &client, backend, cfg)
+
+	go func() {
+		log.Info("startng server", map[string]interface{}{
+			"addr": server.Addr,
+			"tls":  server.TLSConfig != nil,
+		})
+		listen := server.ListenAndServe
+		if server.TLSConfig != nil {
+			// Certificate is provided by TLS config.
+			listen = func() error { return server.ListenAndServeTLS("", "") }
+		}
+		if err := listen(); err != nil {
+			errChan <- errors.Wrap(err, "failed to serve grpc")
+		}
+	}()
+
+	// Start admin server.
+	if *adminToken != "" {
+		adminOpts := []httpBroker.Option{
+			httpBroker.WithMiddleware(middleware...),
+		}
+		if cfg.tenants != nil {
+			adminOpts = append(adminOpts, httpBroker.WithTenants(cfg.tenants, *tenantHdr, nil))
+		}
+		adminServer := httpBroker.NewAdminServer(*adminAddr, *adminToken, admin, adminOpts...)
+		go func() {
+			log.Info("startng admin server", map[string]interface{}{
+				"addr": adminServer.Addr,
+			})
+			if err := adminServer.ListenAndServe(); err != nil {
+				errChan <- errors.Wrap(err, "admin server")
+			}
+		}()
+		defer adminServer.Close()
+	}
+
+	// Start debug server.
+	debugServer := setupDebugServer(*debugAddr)
+	go func() {
+		log.Info("startng debug server", map[string]interface{}{
+			"addr": debugServer.Addr,
+		})
+		if err := debugServer.ListenAndServe(); err != nil {
+			errChan <- errors.Wrap(err, "debug server")
+		}
+	}()
+	defer debugServer.Close()
+
+	// Make a channel to listen for an interrupt or terminate signal from the OS.
+	// Use a buffered channel because the signal package requires it.
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-errChan:
+		log.Fatal(errors.Wrap(err, "critical error"), nil)
+	case <-osSignals:
+		log.Info("stop by signal", nil)
+		if err := server.Close(); err != nil {
+			log.Fatal(errors.Wrap(err, "failed to stop server"), nil)
+		}
+	}
+
+	return nil
+}
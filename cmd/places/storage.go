@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/search"
+	boltRepository "github.com/romanyx/places/internal/storage/bolt"
+	redisRepository "github.com/romanyx/places/internal/storage/redis"
+)
+
+// Storage backends.
+const (
+	backendRedis = "redis"
+	backendBolt  = "bolt"
+)
+
+// sweepInterval is an interval of expired
+// entries removal from bolt storage.
+const sweepInterval = 10 * time.Minute
+
+// storageFlags select and configure storage backend.
+type storageFlags struct {
+	backend      string
+	redisURL     string
+	redisTimeout time.Duration
+	boltPath     string
+}
+
+func (f *storageFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.backend, "storage", backendRedis, "storage backend: redis or bolt")
+	fs.StringVar(&f.redisURL, "redis", "127.0.0.1:6379", "redis database URL")
+	fs.DurationVar(&f.redisTimeout, "redis-timeout", time.Second, "timeout of every redis operation, 0 disables it")
+	fs.StringVar(&f.boltPath, "bolt", "places.db", "path to bolt database file")
+}
+
+// store is an opened storage backend.
+type store struct {
+	search.Repository
+	// close releases backend resources.
+	close func() error
+	// ping checks backend availability, it is
+	// nil for embedded backends.
+	ping func() error
+	// cooldown is shared cooldown of upstream
+	// requests, it is nil for embedded backends.
+	cooldown search.Cooldown
+}
+
+func (f *storageFlags) open() (store, error) {
+	switch f.backend {
+	case backendRedis:
+		log.Info("connectng to redis", map[string]interface{}{
+			"addr": f.redisURL,
+		})
+		client := redis.NewClient(&redis.Options{
+			Addr: f.redisURL,
+		})
+		client.AddHook(redisRepository.TracingHook{})
+		s := store{
+			Repository: redisRepository.NewRepository(client, redisRepository.WithTimeout(f.redisTimeout)),
+			close:      client.Close,
+			cooldown:   redisRepository.NewCooldown(client),
+			ping: func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), storageCheckTimeout)
+				defer cancel()
+				_, err := client.Ping(ctx).Result()
+				return errors.Wrap(err, "ping")
+			},
+		}
+		return s, nil
+	case backendBolt:
+		log.Info("opening bolt database", map[string]interface{}{
+			"path": f.boltPath,
+		})
+		r, err := boltRepository.Open(f.boltPath)
+		if err != nil {
+			return store{}, errors.Wrap(err, "open bolt")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go r.Sweep(ctx, sweepInterval)
+		s := store{
+			Repository: r,
+			close: func() error {
+				cancel()
+				return r.Close()
+			},
+		}
+		return s, nil
+	default:
+		return store{}, errors.Errorf("unknown storage backend: %s", f.backend)
+	}
+}
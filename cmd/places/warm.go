@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+// refresher requests places and caches them.
+type refresher interface {
+	Refresh(context.Context, search.Params) ([]place.Model, error)
+}
+
+// warmCommand fills cache with places of terms read
+// from stdin, one term per line, in every locale.
+func warmCommand(args []string) error {
+	fs := flag.NewFlagSet("warm", flag.ExitOnError)
+	var (
+		locales = fs.String("locales", "en,ru", "comma separated locales of places")
+		types   = fs.String("types", "", "comma separated types of places")
+		workers = fs.Int("workers", 4, "number of concurrent requests")
+		tid     = fs.String("tenant", "", "id of tenant which cache is filled, empty fills cache of the default tenant")
+	)
+	var sf serviceFlags
+	sf.register(fs)
+	fs.Parse(args)
+	if *workers < 1 {
+		return errors.Errorf("workers %d is less than 1", *workers)
+	}
+
+	opts, _, err := sf.options()
+	if err != nil {
+		return err
+	}
+	s, err := sf.open()
+	if err != nil {
+		return err
+	}
+	defer s.close()
+	cfg := config{
+		searchOpts:    opts,
+		cooldown:      sf.cooldown,
+		cooldownStore: s.cooldown,
+		requesterOpts: sf.requesterOptions(),
+	}
+	if cfg.tenants, err = sf.registry(); err != nil {
+		return err
+	}
+	service, err := setupService(&http.Client{}, s, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := withTenant(context.Background(), cfg.tenants, *tid)
+	if err != nil {
+		return err
+	}
+	return warm(ctx, service, os.Stdin, splitList(*locales), splitList(*types), *workers)
+}
+
+func warm(ctx context.Context, r refresher, in io.Reader, locales, types []string, workers int) error {
+	// Without workers nobody receives jobs.
+	if workers < 1 {
+		return errors.Errorf("workers %d is less than 1", workers)
+	}
+
+	jobs := make(chan search.Params)
+	var (
+		wg            sync.WaitGroup
+		mu            sync.Mutex
+		total, failed int
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				_, err := r.Refresh(ctx, p)
+				mu.Lock()
+				total++
+				if err != nil {
+					failed++
+					log.Warn(errors.Wrap(err, "refresh"), map[string]interface{}{
+						"term":   p.Term,
+						"locale": p.Locale,
+					})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		term := strings.TrimSpace(s.Text())
+		if term == "" {
+			continue
+		}
+		for _, locale := range locales {
+			jobs <- search.Params{Term: term, Locale: locale, Types: types}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := s.Err(); err != nil {
+		return errors.Wrap(err, "read terms")
+	}
+
+	log.Info("cache warmed", map[string]interface{}{
+		"searches": total,
+		"failed":   failed,
+	})
+	if failed > 0 {
+		return errors.Errorf("%d of %d searches failed", failed, total)
+	}
+	return nil
+}
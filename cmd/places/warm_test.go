@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestWarm(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		refreshed []search.Params
+	)
+	r := refresherFunc(func(ctx context.Context, p search.Params) ([]place.Model, error) {
+		mu.Lock()
+		refreshed = append(refreshed, p)
+		mu.Unlock()
+		if p.Term == "zzzz" {
+			return nil, errors.New("bad request")
+		}
+		return nil, nil
+	})
+
+	in := strings.NewReader("Moscow\n\n  Paris \nzzzz\n")
+	err := warm(context.Background(), r, in, []string{"en", "ru"}, nil, 2)
+	if err == nil {
+		t.Error("expected error for failed searches")
+	}
+	if len(refreshed) != 6 {
+		t.Errorf("expected 6 searches got: %d", len(refreshed))
+	}
+	for _, p := range refreshed {
+		if p.Term == "" || strings.TrimSpace(p.Term) != p.Term {
+			t.Errorf("unexpected term: %q", p.Term)
+		}
+	}
+}
+
+func TestWarmNoWorkers(t *testing.T) {
+	r := refresherFunc(func(ctx context.Context, p search.Params) ([]place.Model, error) {
+		return nil, nil
+	})
+
+	err := warm(context.Background(), r, strings.NewReader("Moscow\n"), []string{"en"}, nil, 0)
+	if err == nil {
+		t.Error("expected error for no workers")
+	}
+}
+
+type refresherFunc func(context.Context, search.Params) ([]place.Model, error)
+
+func (f refresherFunc) Refresh(ctx context.Context, p search.Params) ([]place.Model, error) {
+	return f(ctx, p)
+}
@@ -1,9 +1,64 @@
 package broker
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	// ErrBadRequest returns when aviasales responds
 	// with bad request status.
 	ErrBadRequest = errors.New("bad request")
 )
+
+// Kinds of Error, check kind with errors.Is.
+var (
+	// ErrRateLimited is a kind of error when aviasales
+	// responds with too many requests status.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnavailable is a kind of error when aviasales
+	// can not be reached or responds with unexpected status.
+	ErrUnavailable = errors.New("upstream unavailable")
+	// ErrMalformed is a kind of error when response
+	// of aviasales can not be decoded.
+	ErrMalformed = errors.New("malformed response")
+	// ErrTimeout is a kind of error when aviasales
+	// does not respond in time.
+	ErrTimeout = errors.New("upstream timeout")
+)
+
+// Error represents failed request to aviasales.
+type Error struct {
+	// Kind is one of error kinds.
+	Kind error
+	// Status is a status code of response, it is
+	// zero when there was no response.
+	Status int
+	// RetryAfter is a delay before next request asked
+	// by aviasales, it is zero when not set.
+	RetryAfter time.Duration
+	// Err is an underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Status != 0 {
+		msg = fmt.Sprintf("%s: status code %d", msg, e.Status)
+	}
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+	return msg
+}
+
+// Is reports whether error is of target kind.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
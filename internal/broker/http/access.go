@@ -0,0 +1,190 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/search"
+)
+
+// AccessLog configures access log middleware.
+type AccessLog struct {
+	// Output is a destination for log lines.
+	Output io.Writer
+	// SampleRate is a fraction of requests which will be
+	// logged, in range (0, 1]. Requests which finished with
+	// server error are logged regardless of sample rate.
+	SampleRate float64
+	// Exclude contains request paths which will not be logged.
+	Exclude []string
+}
+
+// accessEntry represents single line of access log.
+type accessEntry struct {
+	Time      string  `json:"time"`
+	Method    string  `json:"method"`
+	Route     string  `json:"route"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	Latency   float64 `json:"latency_ms"`
+	Size      int     `json:"size"`
+	RemoteIP  string  `json:"remote_ip"`
+	UserAgent string  `json:"user_agent"`
+	Cached    bool    `json:"cached"`
+	Degraded  bool    `json:"degraded"`
+	Corrected string  `json:"corrected,omitempty"`
+	TraceID   string  `json:"trace_id"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+// accessLogger writes one JSON line per request.
+type accessLogger struct {
+	next    http.Handler
+	rate    float64
+	exclude map[string]struct{}
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newAccessLogger(next http.Handler, cfg AccessLog) http.Handler {
+	exclude := make(map[string]struct{}, len(cfg.Exclude))
+	for _, path := range cfg.Exclude {
+		exclude[path] = struct{}{}
+	}
+
+	l := accessLogger{
+		next:    next,
+		rate:    cfg.SampleRate,
+		exclude: exclude,
+		enc:     json.NewEncoder(cfg.Output),
+	}
+
+	return &l
+}
+
+// ServeHTTP implements http.Handler.
+func (l *accessLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := l.exclude[r.URL.Path]; ok {
+		l.next.ServeHTTP(w, r)
+		return
+	}
+
+	start := time.Now()
+	ctx, meta := search.WithMeta(r.Context())
+	var route string
+	ctx = context.WithValue(ctx, routeKey{}, &route)
+	rec := statusRecorder{ResponseWriter: w}
+
+	l.next.ServeHTTP(&rec, r.WithContext(ctx))
+
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status < http.StatusInternalServerError && rand.Float64() >= l.rate {
+		return
+	}
+
+	e := accessEntry{
+		Time:      start.UTC().Format(time.RFC3339Nano),
+		Method:    r.Method,
+		Route:     route,
+		Path:      r.URL.Path,
+		Status:    status,
+		Latency:   float64(time.Since(start)) / float64(time.Millisecond),
+		Size:      rec.size,
+		RemoteIP:  remoteIP(r),
+		UserAgent: r.UserAgent(),
+		Cached:    meta.Cached,
+		Degraded:  meta.Degraded,
+		Corrected: meta.Corrected,
+		TraceID:   trace.FromContext(r.Context()).SpanContext().TraceID.String(),
+		RequestID: RequestIDFromContext(r.Context()),
+	}
+
+	l.mu.Lock()
+	err := l.enc.Encode(&e)
+	l.mu.Unlock()
+	if err != nil {
+		log.Warn(errors.Wrap(err, "write access log"), nil)
+	}
+}
+
+type routeKey struct{}
+
+// withRoute tags request with route for metrics and access log.
+func withRoute(h http.Handler, route string) http.Handler {
+	h = ochttp.WithRouteTag(h, route)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*p = route
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder records status code and size of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *statusRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher.
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, so connection
+// can be upgraded to WebSocket.
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack not supported")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Write implements http.ResponseWriter.
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestAccessLogger(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    AccessLog
+		path   string
+		status int
+		expect bool
+	}{
+		{
+			name:   "logged",
+			cfg:    AccessLog{SampleRate: 1},
+			path:   "/places",
+			status: http.StatusOK,
+			expect: true,
+		},
+		{
+			name:   "excluded",
+			cfg:    AccessLog{SampleRate: 1, Exclude: []string{"/places"}},
+			path:   "/places",
+			status: http.StatusOK,
+		},
+		{
+			name:   "not sampled",
+			cfg:    AccessLog{SampleRate: 0},
+			path:   "/places",
+			status: http.StatusOK,
+		},
+		{
+			name:   "server error always logged",
+			cfg:    AccessLog{SampleRate: 0},
+			path:   "/places",
+			status: http.StatusServiceUnavailable,
+			expect: true,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			tc.cfg.Output = &buf
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if m := search.MetaFromContext(r.Context()); m != nil {
+					m.Cached = true
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte("[]"))
+			})
+			mux := http.NewServeMux()
+			mux.Handle("/places", withRoute(h, "/places"))
+			l := newAccessLogger(mux, tc.cfg)
+
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			r.Header.Set("User-Agent", "test")
+			l.ServeHTTP(httptest.NewRecorder(), r)
+
+			if !tc.expect {
+				if buf.Len() != 0 {
+					t.Errorf("unexpected log line: %s", buf.String())
+				}
+				return
+			}
+
+			var got accessEntry
+			if err := json.NewDecoder(&buf).Decode(&got); err != nil {
+				t.Fatalf("decode log line: %v", err)
+			}
+			expect := accessEntry{
+				Method:    http.MethodGet,
+				Route:     "/places",
+				Path:      tc.path,
+				Status:    tc.status,
+				Size:      2,
+				RemoteIP:  "192.0.2.1",
+				UserAgent: "test",
+				Cached:    true,
+			}
+			got.Time, got.Latency, got.TraceID = "", 0, ""
+			if got != expect {
+				t.Errorf("expected: %+v got: %+v", expect, got)
+			}
+		})
+	}
+}
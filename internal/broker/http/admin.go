@@ -0,0 +1,210 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/plugin/ochttp"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/storage"
+)
+
+// Admin represents cache administration interface.
+type Admin interface {
+	List(context.Context) ([]search.Entry, error)
+	Retrieve(context.Context, search.Params) (search.Entry, error)
+	Delete(context.Context, search.Params) error
+	Purge(ctx context.Context, term, locale string) (int, error)
+	Refresh(context.Context, search.Params) ([]place.Model, error)
+}
+
+// adminEntry represents cached entry in admin API.
+type adminEntry struct {
+	Params   search.Params `json:"params"`
+	CachedAt time.Time     `json:"cached_at"`
+	Age      int           `json:"age"`
+	Count    int           `json:"count"`
+	Places   []place.Model `json:"places,omitempty"`
+}
+
+// NewAdminServer initialize http.Server of the admin API. Every
+// request must carry token in Authorization header as a bearer.
+//
+//	GET    /cache                 lists cached entries
+//	DELETE /cache?term=&locale=   deletes entries which match term and locale
+//	GET    /cache/entry?term=...  shows entry with places
+//	DELETE /cache/entry?term=...  deletes entry
+//	POST   /cache/refresh?term=.. requests places and replaces entry
+//
+// Only tenants and middleware options are applied, entries of
+// a tenant are managed when request names it in tenant header,
+// which is honored from any client since they are authorized.
+func NewAdminServer(addr, token string, admin Admin, opts ...Option) *http.Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	h := adminHandler{
+		Admin: admin,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/cache", ochttp.WithRouteTag(httpHandler{handlerFunc(h.handleCache)}, "/cache"))
+	mux.Handle("/cache/entry", ochttp.WithRouteTag(httpHandler{handlerFunc(h.handleEntry)}, "/cache/entry"))
+	mux.Handle("/cache/refresh", ochttp.WithRouteTag(httpHandler{handlerFunc(h.handleRefresh)}, "/cache/refresh"))
+
+	s := http.Server{
+		Addr: addr,
+		Handler: o.withTenants(&ochttp.Handler{
+			Handler: Chain(adminAuth{next: mux, token: token}, append([]Middleware{Recovery()}, o.middleware...)...),
+		}, anyRequest),
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+	}
+
+	return &s
+}
+
+func anyRequest(*http.Request) bool {
+	return true
+}
+
+// handlerFunc allows to use function as Handler.
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Handle implements Handler.
+func (f handlerFunc) Handle(w http.ResponseWriter, r *http.Request) error {
+	return f(w, r)
+}
+
+// adminAuth checks bearer token of requests.
+type adminAuth struct {
+	next  http.Handler
+	token string
+}
+
+// ServeHTTP implements http.Handler.
+func (a adminAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	expect := "Bearer " + a.token
+	got := r.Header.Get("Authorization")
+	if a.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(expect)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="places admin"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	a.next.ServeHTTP(w, r)
+}
+
+type adminHandler struct {
+	Admin
+}
+
+func (h adminHandler) handleCache(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := h.List(r.Context())
+		if err != nil {
+			return errors.Wrap(err, "list entries")
+		}
+
+		result := make([]adminEntry, len(entries))
+		for i, e := range entries {
+			result[i] = newAdminEntry(e)
+			result[i].Places = nil
+		}
+		return writeJSON(w, &result)
+	case http.MethodDelete:
+		q := r.URL.Query()
+		term, locale := q.Get("term"), q.Get("locale")
+		if term == "" && locale == "" {
+			return badRequestResponse(w)
+		}
+
+		deleted, err := h.Purge(r.Context(), term, locale)
+		if err != nil {
+			return errors.Wrap(err, "purge entries")
+		}
+		return writeJSON(w, map[string]int{"deleted": deleted})
+	default:
+		return methodNotAllowedResponse(w)
+	}
+}
+
+func (h adminHandler) handleEntry(w http.ResponseWriter, r *http.Request) error {
+	var params search.Params
+	setParams(&params, r.URL.Query())
+
+	switch r.Method {
+	case http.MethodGet:
+		e, err := h.Retrieve(r.Context(), params)
+		if err != nil {
+			if errors.Cause(err) == storage.ErrCacheNotFound {
+				return notFoundResponse(w)
+			}
+			return errors.Wrap(err, "retrieve entry")
+		}
+
+		result := newAdminEntry(e)
+		return writeJSON(w, &result)
+	case http.MethodDelete:
+		if err := h.Delete(r.Context(), params); err != nil {
+			if errors.Cause(err) == storage.ErrCacheNotFound {
+				return notFoundResponse(w)
+			}
+			return errors.Wrap(err, "delete entry")
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return methodNotAllowedResponse(w)
+	}
+}
+
+func (h adminHandler) handleRefresh(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return methodNotAllowedResponse(w)
+	}
+
+	var params search.Params
+	setParams(&params, r.URL.Query())
+
+	places, err := h.Refresh(r.Context(), params)
+	if err != nil {
+		errorResponse(w, err)
+		return errors.Wrap(err, "refresh entry")
+	}
+
+	return writeJSON(w, &places)
+}
+
+func newAdminEntry(e search.Entry) adminEntry {
+	result := adminEntry{
+		Params:   e.Params,
+		CachedAt: e.CachedAt,
+		Count:    len(e.Places),
+		Places:   e.Places,
+	}
+	if !e.CachedAt.IsZero() {
+		result.Age = int(time.Since(e.CachedAt) / time.Second)
+	}
+
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return errors.Wrap(err, "encode json")
+	}
+
+	return nil
+}
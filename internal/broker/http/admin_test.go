@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/storage"
+)
+
+func TestAdminServer(t *testing.T) {
+	tt := []struct {
+		name       string
+		method     string
+		target     string
+		token      string
+		expectCode int
+		expectBody string
+	}{
+		{
+			name:       "unauthorized",
+			method:     http.MethodGet,
+			target:     "/cache",
+			token:      "wrong",
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			target:     "/cache",
+			token:      "secret",
+			expectCode: http.StatusOK,
+			expectBody: `"count":1`,
+		},
+		{
+			name:       "entry",
+			method:     http.MethodGet,
+			target:     "/cache/entry?term=Moscow&locale=ru",
+			token:      "secret",
+			expectCode: http.StatusOK,
+			expectBody: `"slug":"MOW"`,
+		},
+		{
+			name:       "entry not found",
+			method:     http.MethodGet,
+			target:     "/cache/entry?term=Paris&locale=ru",
+			token:      "secret",
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			target:     "/cache/entry?term=Moscow&locale=ru",
+			token:      "secret",
+			expectCode: http.StatusNoContent,
+		},
+		{
+			name:       "purge",
+			method:     http.MethodDelete,
+			target:     "/cache?term=Moscow",
+			token:      "secret",
+			expectCode: http.StatusOK,
+			expectBody: `{"deleted":1}`,
+		},
+		{
+			name:       "purge without filter",
+			method:     http.MethodDelete,
+			target:     "/cache",
+			token:      "secret",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "refresh",
+			method:     http.MethodPost,
+			target:     "/cache/refresh?term=Moscow&locale=ru",
+			token:      "secret",
+			expectCode: http.StatusOK,
+			expectBody: `"slug":"MOW"`,
+		},
+	}
+
+	s := NewAdminServer("", "secret", adminStub{})
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(tc.method, tc.target, nil)
+			r.Header.Set("Authorization", "Bearer "+tc.token)
+			w := httptest.NewRecorder()
+			s.Handler.ServeHTTP(w, r)
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected code: %d got: %d", tc.expectCode, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tc.expectBody) {
+				t.Errorf("expected body to contain: %s got: %s", tc.expectBody, w.Body.String())
+			}
+		})
+	}
+}
+
+var moscow = search.Entry{
+	Params:   search.Params{Term: "Moscow", Locale: "ru"},
+	Places:   []place.Model{{Slug: "MOW"}},
+	CachedAt: time.Now(),
+}
+
+type adminStub struct{}
+
+func (adminStub) List(context.Context) ([]search.Entry, error) {
+	return []search.Entry{moscow}, nil
+}
+
+func (adminStub) Retrieve(ctx context.Context, p search.Params) (search.Entry, error) {
+	if p.Term != moscow.Params.Term {
+		return search.Entry{}, storage.ErrCacheNotFound
+	}
+	return moscow, nil
+}
+
+func (adminStub) Delete(ctx context.Context, p search.Params) error {
+	if p.Term != moscow.Params.Term {
+		return storage.ErrCacheNotFound
+	}
+	return nil
+}
+
+func (adminStub) Purge(ctx context.Context, term, locale string) (int, error) {
+	return 1, nil
+}
+
+func (adminStub) Refresh(ctx context.Context, p search.Params) ([]place.Model, error) {
+	return moscow.Places, nil
+}
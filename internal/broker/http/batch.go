@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/recovery"
+	"github.com/romanyx/places/internal/search"
+)
+
+const (
+	// maxBatchSize is a maximum number of params in batch.
+	maxBatchSize = 50
+	// maxBatchBody is a maximum size of batch request body.
+	maxBatchBody = 1 << 20
+	// batchWorkers is a number of concurrent searches
+	// performed for a batch.
+	batchWorkers = 8
+)
+
+// batchItem represents result of the single search in batch.
+// Places are set when status is 200, error otherwise.
+type batchItem struct {
+	Places []place.Model `json:"places"`
+	Status int           `json:"status"`
+	Error  string        `json:"error,omitempty"`
+}
+
+type batchHandler struct {
+	Searcher
+}
+
+func newBatchHandler(searcher Searcher) http.Handler {
+	batchHandler := batchHandler{
+		Searcher: searcher,
+	}
+
+	h := httpHandler{batchHandler}
+	return h
+}
+
+// Handle performs searches for the list of params concurrently.
+// Whole batch is rejected with 400 when body is malformed or
+// empty and with 413 when it is larger than maxBatchBody or
+// contains more than maxBatchSize params. Otherwise responds
+// with 200 and list of items in the same order as params,
+// each item carries its own status, so failure of one search
+// does not fail the whole batch.
+func (h batchHandler) Handle(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return methodNotAllowedResponse(w)
+	}
+
+	var params []search.Params
+	body := http.MaxBytesReader(w, r.Body, maxBatchBody)
+	if err := json.NewDecoder(body).Decode(&params); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return entityTooLargeResponse(w)
+		}
+		return badRequestResponse(w)
+	}
+	if len(params) == 0 {
+		return badRequestResponse(w)
+	}
+	if len(params) > maxBatchSize {
+		return entityTooLargeResponse(w)
+	}
+
+	// Params without locale inherit it from the header.
+	locale := requestLocale(r)
+	for i := range params {
+		if params[i].Locale == "" {
+			params[i].Locale = locale
+		}
+	}
+
+	items := make([]batchItem, len(params))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < batchWorkers && i < len(params); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				items[j] = h.search(r.Context(), params[j])
+			}
+		}()
+	}
+	for i := range params {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := json.NewEncoder(w).Encode(&items); err != nil {
+		return errors.Wrap(err, "encode json")
+	}
+
+	return nil
+}
+
+// search searches places of one batch item, panic of the
+// search fails the item only.
+func (h batchHandler) search(ctx context.Context, p search.Params) (item batchItem) {
+	defer func() {
+		if v := recover(); v != nil {
+			recovery.Report(ctx, "batch", v)
+			item = batchItem{
+				Status: http.StatusInternalServerError,
+				Error:  http.StatusText(http.StatusInternalServerError),
+			}
+		}
+	}()
+
+	// Every search gets own meta, so concurrent
+	// searches do not share it.
+	ctx, _ = search.WithMeta(ctx)
+	places, err := h.Search(ctx, p)
+	if err != nil {
+		status := errorStatus(err)
+		return batchItem{Status: status, Error: http.StatusText(status)}
+	}
+	return batchItem{Places: places, Status: http.StatusOK}
+}
+
+func entityTooLargeResponse(w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	return nil
+}
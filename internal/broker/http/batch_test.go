@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/romanyx/places/internal/broker"
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestBatchHandler(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		expectCode int
+		expectBody string
+	}{
+		{
+			name:       "partial failure",
+			body:       `[{"term":"Moscow"},{"term":"zzzz"},{"term":"Paris"}]`,
+			expectCode: http.StatusOK,
+			expectBody: `[{"places":[{"slug":"Moscow","subtitle":"","title":""}],"status":200},` +
+				`{"places":null,"status":400,"error":"Bad Request"},` +
+				`{"places":[{"slug":"Paris","subtitle":"","title":""}],"status":200}]`,
+		},
+		{
+			name:       "panic",
+			body:       `[{"term":"Moscow"},{"term":"panic"}]`,
+			expectCode: http.StatusOK,
+			expectBody: `[{"places":[{"slug":"Moscow","subtitle":"","title":""}],"status":200},` +
+				`{"places":null,"status":500,"error":"Internal Server Error"}]`,
+		},
+		{
+			name:       "malformed",
+			body:       `{"term":"Moscow"}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "empty",
+			body:       `[]`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "body too large",
+			body:       `[{"term":"` + strings.Repeat("M", maxBatchBody) + `"}]`,
+			expectCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "too large",
+			body:       "[" + strings.Repeat(`{"term":"Moscow"},`, maxBatchSize) + `{"term":"Moscow"}]`,
+			expectCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	h := newBatchHandler(searcherFunc(func(ctx context.Context, p search.Params) ([]place.Model, error) {
+		switch p.Term {
+		case "zzzz":
+			return nil, broker.ErrBadRequest
+		case "panic":
+			panic("search failed")
+		}
+		return []place.Model{{Slug: p.Term}}, nil
+	}))
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/places/batch", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected code: %d got: %d", tc.expectCode, w.Code)
+				return
+			}
+
+			if got := strings.TrimSpace(w.Body.String()); tc.expectBody != "" && got != tc.expectBody {
+				t.Errorf("expected: %s got: %s", tc.expectBody, got)
+			}
+		})
+	}
+}
+
+type searcherFunc func(context.Context, search.Params) ([]place.Model, error)
+
+func (f searcherFunc) Search(ctx context.Context, p search.Params) ([]place.Model, error) {
+	return f(ctx, p)
+}
+
+func (f searcherFunc) Lookup(ctx context.Context, slug, locale string) (place.Model, error) {
+	return place.Model{}, search.ErrNotFound
+}
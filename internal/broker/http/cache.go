@@ -0,0 +1,116 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/search"
+)
+
+// staleWarning is a warning of the response
+// served from cache because request failed.
+const staleWarning = `110 - "Response is Stale"`
+
+// responder writes successful responses.
+type responder struct {
+	// maxAge is a time during which response may be cached.
+	maxAge time.Duration
+	// jsonp enables JSONP callback query param.
+	jsonp bool
+	// tenantHeader is a request header which names
+	// tenant, responses vary by it.
+	tenantHeader string
+}
+
+// write writes v as JSON with caching headers. Freshness is
+// based on meta, so stale results are not stored by caches and
+// degraded ones are not stored at all, since they would be
+// served after upstream recovers, and
+// ETag is computed from the body, so conditional request with the
+// same result set is answered with 304. When JSONP is enabled and
+// request has callback param, JSON is wrapped into its call.
+func (rs responder) write(w http.ResponseWriter, r *http.Request, meta *search.Meta, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "encode json")
+	}
+	body = append(body, '\n')
+
+	contentType := "application/json"
+	if callback := r.URL.Query().Get(callbackParam); rs.jsonp && callback != "" {
+		if !validCallback(callback) {
+			return badRequestResponse(w)
+		}
+		body = wrapCallback(callback, body)
+		contentType = "application/javascript"
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Add("Vary", "Accept-Language, Accept-Encoding")
+	if rs.tenantHeader != "" {
+		h.Add("Vary", rs.tenantHeader)
+	}
+
+	var age time.Duration
+	if !meta.CachedAt.IsZero() {
+		age = time.Since(meta.CachedAt)
+		h.Set("Age", strconv.Itoa(int(age/time.Second)))
+	}
+
+	switch {
+	case meta.Degraded:
+		h.Set("Cache-Control", "no-store")
+	case meta.Stale:
+		h.Set("Cache-Control", "public, max-age=0, must-revalidate")
+		h.Set("Warning", staleWarning)
+	case rs.maxAge > 0:
+		fresh := rs.maxAge - age
+		if fresh < 0 {
+			fresh = 0
+		}
+		h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(fresh/time.Second)))
+	default:
+		h.Set("Cache-Control", "no-cache")
+	}
+
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	h.Set("Content-Type", contentType)
+	if _, err := w.Write(body); err != nil {
+		return errors.Wrap(err, "write body")
+	}
+
+	return nil
+}
+
+// etagMatch reports whether If-None-Match header value
+// matches etag using weak comparison.
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
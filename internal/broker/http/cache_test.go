@@ -0,0 +1,118 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestResponderWrite(t *testing.T) {
+	places := []string{"MOW"}
+	w := httptest.NewRecorder()
+	rs := responder{maxAge: time.Minute, jsonp: true, tenantHeader: "X-Places-Tenant"}
+	rs.write(w, httptest.NewRequest(http.MethodGet, "/places", nil), &search.Meta{}, places)
+	etag := w.Header().Get("ETag")
+
+	tt := []struct {
+		name          string
+		meta          search.Meta
+		ifNoneMatch   string
+		query         string
+		expectBody    string
+		expectCode    int
+		expectControl string
+		expectWarning bool
+		expectAge     bool
+	}{
+		{
+			name:          "fresh",
+			expectCode:    http.StatusOK,
+			expectControl: "public, max-age=60",
+		},
+		{
+			name:          "stale",
+			meta:          search.Meta{Cached: true, Stale: true, CachedAt: time.Now().Add(-time.Hour)},
+			expectCode:    http.StatusOK,
+			expectControl: "public, max-age=0, must-revalidate",
+			expectWarning: true,
+			expectAge:     true,
+		},
+		{
+			name:          "degraded",
+			meta:          search.Meta{Degraded: true},
+			expectCode:    http.StatusOK,
+			expectControl: "no-store",
+		},
+		{
+			name:          "not modified",
+			ifNoneMatch:   `"other", ` + etag,
+			expectCode:    http.StatusNotModified,
+			expectControl: "public, max-age=60",
+		},
+		{
+			name:          "jsonp",
+			query:         "?callback=widget.onPlaces",
+			expectCode:    http.StatusOK,
+			expectControl: "public, max-age=60",
+			expectBody:    "/**/widget.onPlaces([\"MOW\"]\n);\n",
+		},
+		{
+			name:       "jsonp invalid callback",
+			query:      "?callback=alert(1)",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:          "modified",
+			ifNoneMatch:   `"other"`,
+			expectCode:    http.StatusOK,
+			expectControl: "public, max-age=60",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/places"+tc.query, nil)
+			if tc.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			if err := rs.write(w, r, &tc.meta, places); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected code: %d got: %d", tc.expectCode, w.Code)
+			}
+			if tc.expectCode == http.StatusBadRequest {
+				return
+			}
+			if tc.expectBody != "" && w.Body.String() != tc.expectBody {
+				t.Errorf("expected body: %q got: %q", tc.expectBody, w.Body.String())
+			}
+			// JSONP body differs, so does its ETag.
+			if got := w.Header().Get("ETag"); (got == etag) != (tc.query == "") {
+				t.Errorf("unexpected etag: %s for query: %q", got, tc.query)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tc.expectControl {
+				t.Errorf("expected cache control: %s got: %s", tc.expectControl, got)
+			}
+			if got := w.Header().Get("Warning") != ""; got != tc.expectWarning {
+				t.Errorf("expected warning: %v got: %v", tc.expectWarning, got)
+			}
+			if got := w.Header().Get("Age") != ""; got != tc.expectAge {
+				t.Errorf("expected age: %v got: %v", tc.expectAge, got)
+			}
+			expectVary := "Accept-Language, Accept-Encoding, X-Places-Tenant"
+			if got := strings.Join(w.Header().Values("Vary"), ", "); got != expectVary {
+				t.Errorf("expected vary: %s got: %s", expectVary, got)
+			}
+		})
+	}
+}
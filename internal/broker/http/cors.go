@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures cross-origin resource sharing.
+type CORS struct {
+	// AllowedOrigins contains origins allowed to make
+	// requests, "*" allows any origin.
+	AllowedOrigins []string
+	// AllowedMethods contains methods allowed for
+	// cross-origin requests.
+	AllowedMethods []string
+	// AllowedHeaders contains request headers allowed
+	// for cross-origin requests.
+	AllowedHeaders []string
+	// ExposedHeaders contains response headers which
+	// browsers expose to clients.
+	ExposedHeaders []string
+	// MaxAge is a time during which preflight response
+	// may be cached by browsers.
+	MaxAge time.Duration
+}
+
+type corsHandler struct {
+	next    http.Handler
+	any     bool
+	origins map[string]struct{}
+	allowed map[string]struct{}
+	methods string
+	headers string
+	exposed string
+	maxAge  string
+}
+
+func newCORSHandler(next http.Handler, cfg CORS) http.Handler {
+	h := corsHandler{
+		next:    next,
+		origins: make(map[string]struct{}),
+		allowed: make(map[string]struct{}),
+		methods: strings.Join(cfg.AllowedMethods, ", "),
+		headers: strings.Join(cfg.AllowedHeaders, ", "),
+		exposed: strings.Join(cfg.ExposedHeaders, ", "),
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			h.any = true
+		}
+		h.origins[origin] = struct{}{}
+	}
+	for _, method := range cfg.AllowedMethods {
+		h.allowed[strings.ToUpper(method)] = struct{}{}
+	}
+	if cfg.MaxAge > 0 {
+		h.maxAge = strconv.Itoa(int(cfg.MaxAge / time.Second))
+	}
+
+	return &h
+}
+
+// ServeHTTP implements http.Handler. Preflight requests are
+// answered without calling next handler, with 204 if origin and
+// method are allowed and with 403 otherwise.
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if !preflight {
+		if origin != "" && h.originAllowed(origin) {
+			h.setOrigin(header, origin)
+			if h.exposed != "" {
+				header.Set("Access-Control-Expose-Headers", h.exposed)
+			}
+		}
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if _, ok := h.allowed[method]; !ok || !h.originAllowed(origin) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	h.setOrigin(header, origin)
+	header.Set("Access-Control-Allow-Methods", h.methods)
+	if h.headers != "" {
+		header.Set("Access-Control-Allow-Headers", h.headers)
+	}
+	if h.maxAge != "" {
+		header.Set("Access-Control-Max-Age", h.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *corsHandler) originAllowed(origin string) bool {
+	if h.any {
+		return true
+	}
+	_, ok := h.origins[origin]
+	return ok
+}
+
+func (h *corsHandler) setOrigin(header http.Header, origin string) {
+	if h.any {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+}
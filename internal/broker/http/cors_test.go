@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORSHandler(t *testing.T) {
+	cfg := CORS{
+		AllowedOrigins: []string{"https://example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         time.Hour,
+	}
+
+	tt := []struct {
+		name         string
+		method       string
+		origin       string
+		preflight    string
+		expectCode   int
+		expectOrigin string
+		expectMaxAge string
+	}{
+		{
+			name:         "preflight",
+			method:       http.MethodOptions,
+			origin:       "https://example.com",
+			preflight:    http.MethodPost,
+			expectCode:   http.StatusNoContent,
+			expectOrigin: "https://example.com",
+			expectMaxAge: "3600",
+		},
+		{
+			name:       "preflight origin not allowed",
+			method:     http.MethodOptions,
+			origin:     "https://evil.com",
+			preflight:  http.MethodGet,
+			expectCode: http.StatusForbidden,
+		},
+		{
+			name:       "preflight method not allowed",
+			method:     http.MethodOptions,
+			origin:     "https://example.com",
+			preflight:  http.MethodDelete,
+			expectCode: http.StatusForbidden,
+		},
+		{
+			name:         "simple",
+			method:       http.MethodGet,
+			origin:       "https://example.com",
+			expectCode:   http.StatusOK,
+			expectOrigin: "https://example.com",
+		},
+		{
+			name:       "simple origin not allowed",
+			method:     http.MethodGet,
+			origin:     "https://evil.com",
+			expectCode: http.StatusOK,
+		},
+	}
+
+	h := newCORSHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), cfg)
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(tc.method, "/places", nil)
+			r.Header.Set("Origin", tc.origin)
+			if tc.preflight != "" {
+				r.Header.Set("Access-Control-Request-Method", tc.preflight)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected code: %d got: %d", tc.expectCode, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.expectOrigin {
+				t.Errorf("expected origin: %q got: %q", tc.expectOrigin, got)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != tc.expectMaxAge {
+				t.Errorf("expected max age: %q got: %q", tc.expectMaxAge, got)
+			}
+		})
+	}
+}
@@ -2,28 +2,44 @@ package http
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/romanyx/places/internal/broker"
 	"github.com/romanyx/places/internal/log"
 	"github.com/romanyx/places/internal/place"
 	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/tenant"
 )
 
 const (
 	timeout = 30 * time.Second
+	// retryAfter is a delay suggested to clients when
+	// server is overloaded or aviasales rate limits it
+	// without saying for how long.
+	retryAfter = time.Second
+	// degradedHeader marks responses found in
+	// fallback dataset.
+	degradedHeader = "X-Places-Degraded"
+	// correctedHeader carries URL escaped term which
+	// gave places instead of requested one.
+	correctedHeader = "X-Places-Did-You-Mean"
 )
 
 // Searcher represents search interface.
 type Searcher interface {
 	Search(context.Context, search.Params) ([]place.Model, error)
+	Lookup(ctx context.Context, slug, locale string) (place.Model, error)
 }
 
 // Handler allows to handle requests.
@@ -33,11 +49,13 @@ type Handler interface {
 
 type searchHandler struct {
 	Searcher
+	responder
 }
 
-func newSearchHandler(searcher Searcher) http.Handler {
+func newSearchHandler(searcher Searcher, rs responder) http.Handler {
 	searchHandler := searchHandler{
-		Searcher: searcher,
+		Searcher:  searcher,
+		responder: rs,
 	}
 
 	h := httpHandler{searchHandler}
@@ -54,24 +72,40 @@ func (h searchHandler) Handle(w http.ResponseWriter, r *http.Request) error {
 	}
 	var params search.Params
 	setParams(&params, r.Form)
+	if params.Locale == "" {
+		params.Locale = requestLocale(r)
+	}
+	opts, err := parseListOptions(r.Form)
+	if err != nil {
+		return h.writeError(w, r, broker.ErrBadRequest)
+	}
 
-	places, err := h.Search(r.Context(), params)
+	ctx, meta := metaContext(r.Context())
+	places, err := h.Search(ctx, params)
 	if err != nil {
-		switch errors.Cause(err) {
-		case search.ErrUnavailable:
-			return unavailableResponse(w)
-		case broker.ErrBadRequest:
-			return badRequestResponse(w)
-		default:
-			return internalServerErrorResponse(w)
-		}
+		return h.writeError(w, r, err)
 	}
 
-	if err := json.NewEncoder(w).Encode(&places); err != nil {
-		return errors.Wrap(err, "encode json")
+	if meta.Degraded {
+		w.Header().Set(degradedHeader, "true")
+	}
+	if meta.Corrected != "" {
+		w.Header().Set(correctedHeader, url.PathEscape(meta.Corrected))
 	}
 
-	return nil
+	places, total := opts.apply(places)
+	w.Header().Set(totalHeader, strconv.Itoa(total))
+
+	return h.write(w, r, meta, &places)
+}
+
+// metaContext returns ctx which carries search meta, meta
+// created by middleware is reused.
+func metaContext(ctx context.Context) (context.Context, *search.Meta) {
+	if m := search.MetaFromContext(ctx); m != nil {
+		return ctx, m
+	}
+	return search.WithMeta(ctx)
 }
 
 func setParams(params *search.Params, f url.Values) {
@@ -84,16 +118,125 @@ func setParams(params *search.Params, f url.Values) {
 	params.Types = f["types[]"]
 }
 
+// Option configures server.
+type Option func(*options)
+
+type options struct {
+	accessLog *AccessLog
+	cors      *CORS
+	responder responder
+	debounce  time.Duration
+	tenants   *tenant.Registry
+	header    string
+	proxies   []*net.IPNet
+	// middleware wraps handler inside tracing.
+	middleware []Middleware
+	tls        *tls.Config
+	h2c        bool
+}
+
+// WithAccessLog enables access log.
+func WithAccessLog(cfg AccessLog) Option {
+	return func(o *options) {
+		o.accessLog = &cfg
+	}
+}
+
+// WithMaxAge sets time during which successful
+// responses may be cached by clients.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.responder.maxAge = maxAge
+	}
+}
+
+// WithJSONP enables JSONP, responses are wrapped into
+// the function from callback query param.
+func WithJSONP() Option {
+	return func(o *options) {
+		o.responder.jsonp = true
+	}
+}
+
+// WithCORS enables cross-origin resource sharing.
+func WithCORS(cfg CORS) Option {
+	return func(o *options) {
+		o.cors = &cfg
+	}
+}
+
+// WithDebounce sets pause after the last term sent to
+// type-ahead endpoint before search starts.
+func WithDebounce(debounce time.Duration) Option {
+	return func(o *options) {
+		o.debounce = debounce
+	}
+}
+
+// WithTenants enables tenants, tenant of request is named
+// by header or resolved by host. Header is honored only for
+// requests of proxies, which are trusted to set it, admin
+// server honors header of any authorized request.
+func WithTenants(registry *tenant.Registry, header string, proxies []*net.IPNet) Option {
+	return func(o *options) {
+		o.tenants = registry
+		o.header = header
+		o.proxies = proxies
+		o.responder.tenantHeader = header
+	}
+}
+
+// WithTLSConfig enables TLS, server must be started with
+// ListenAndServeTLS with empty file names. HTTP/2 is
+// negotiated with clients over TLS.
+func WithTLSConfig(c *tls.Config) Option {
+	return func(o *options) {
+		o.tls = c
+	}
+}
+
+// WithH2C enables HTTP/2 without TLS for clients which
+// know the server supports it or upgrade connection.
+// It is ignored when TLS is enabled.
+func WithH2C() Option {
+	return func(o *options) {
+		o.h2c = true
+	}
+}
+
 // NewServer initialize http.Server.
-func NewServer(addr string, searcher Searcher) *http.Server {
+func NewServer(addr string, searcher Searcher, opts ...Option) *http.Server {
+	o := options{
+		debounce: typeaheadDebounce,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	mux := http.NewServeMux()
-	mux.Handle("/places", ochttp.WithRouteTag(newSearchHandler(searcher), "/places"))
+	mux.Handle("/places", withRoute(newSearchHandler(searcher, o.responder), "/places"))
+	mux.Handle("/places/batch", withRoute(newBatchHandler(searcher), "/places/batch"))
+	mux.Handle("/places/typeahead", withRoute(newTypeaheadHandler(searcher, o.debounce, o.cors), "/places/typeahead"))
+	mux.Handle("/places/", withRoute(newLookupHandler(searcher, o.responder), "/places/{slug}"))
+
+	// Handler panics are recovered inside access log and
+	// CORS, so 500 is logged and readable by browsers, the
+	// outer recovery handles panics of middleware.
+	h := Recovery()(mux)
+	if o.cors != nil {
+		h = newCORSHandler(h, *o.cors)
+	}
+	if o.accessLog != nil {
+		h = newAccessLogger(h, *o.accessLog)
+	}
+	h = Chain(h, append([]Middleware{Recovery()}, o.middleware...)...)
 
 	s := http.Server{
 		Addr: addr,
-		Handler: &ochttp.Handler{
-			Handler: mux,
-		},
+		Handler: o.withH2C(o.withTenants(&ochttp.Handler{
+			Handler: h,
+		}, o.fromProxy)),
+		TLSConfig:    o.tls,
 		ReadTimeout:  timeout,
 		WriteTimeout: timeout,
 	}
@@ -101,6 +244,31 @@ func NewServer(addr string, searcher Searcher) *http.Server {
 	return &s
 }
 
+// withTenants wraps handler with tenant resolution when
+// tenants are enabled, trusted reports whether request
+// may name tenant in header. It wraps ochttp handler, so
+// server metrics are tagged with tenant.
+func (o options) withTenants(h http.Handler, trusted func(*http.Request) bool) http.Handler {
+	if o.tenants == nil {
+		return h
+	}
+	return newTenantHandler(h, o.tenants, o.header, trusted)
+}
+
+// fromProxy reports whether request came from trusted proxy.
+func (o options) fromProxy(r *http.Request) bool {
+	return containsIP(o.proxies, remoteIP(r))
+}
+
+// withH2C wraps handler with HTTP/2 cleartext handler
+// when h2c is enabled without TLS.
+func (o options) withH2C(h http.Handler) http.Handler {
+	if !o.h2c || o.tls != nil {
+		return h
+	}
+	return h2c.NewHandler(h, &http2.Server{})
+}
+
 // httpHandler allows to implement ServeHTTP for Handler.
 type httpHandler struct {
 	Handler
@@ -116,14 +284,55 @@ func (h httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func internalServerErrorResponse(w http.ResponseWriter) error {
-	w.WriteHeader(http.StatusInternalServerError)
+// errorResponse responds with status which corresponds to
+// the search error. Retry-After is set when aviasales asked
+// to retry later or server is overloaded.
+func errorResponse(w http.ResponseWriter, err error) error {
+	setRetryAfter(w, err)
+	w.WriteHeader(errorStatus(err))
 	return nil
 }
 
-func unavailableResponse(w http.ResponseWriter) error {
-	w.WriteHeader(http.StatusServiceUnavailable)
-	return nil
+func setRetryAfter(w http.ResponseWriter, err error) {
+	if d := retryDelay(err); d > 0 {
+		seconds := (d + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+}
+
+// errorStatus returns status code which corresponds
+// to the search error. Kinds of aviasales errors are
+// checked first, since UnavailableError wraps them.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, broker.ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, search.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, broker.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, broker.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, broker.ErrMalformed):
+		return http.StatusBadGateway
+	case errors.Is(err, search.ErrUnavailable), errors.Is(err, search.ErrOverloaded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// retryDelay returns delay before client should retry,
+// zero when retry is not expected to help soon.
+func retryDelay(err error) time.Duration {
+	var upstream *broker.Error
+	if errors.As(err, &upstream) && upstream.RetryAfter > 0 {
+		return upstream.RetryAfter
+	}
+	if errors.Is(err, search.ErrOverloaded) || errors.Is(err, broker.ErrRateLimited) {
+		return retryAfter
+	}
+	return 0
 }
 
 func badRequestResponse(w http.ResponseWriter) error {
@@ -131,6 +340,11 @@ func badRequestResponse(w http.ResponseWriter) error {
 	return nil
 }
 
+func notFoundResponse(w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusNotFound)
+	return nil
+}
+
 func methodNotAllowedResponse(w http.ResponseWriter) error {
 	w.WriteHeader(http.StatusMethodNotAllowed)
 	return nil
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/broker"
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tt := []struct {
+		name        string
+		err         error
+		expectCode  int
+		expectRetry string
+	}{
+		{
+			name:       "bad request",
+			err:        broker.ErrBadRequest,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "not found",
+			err:        search.ErrNotFound,
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:        "rate limited",
+			err:         &search.UnavailableError{Err: &broker.Error{Kind: broker.ErrRateLimited, RetryAfter: 90 * time.Second}},
+			expectCode:  http.StatusTooManyRequests,
+			expectRetry: "90",
+		},
+		{
+			name:        "rate limited without delay",
+			err:         &search.UnavailableError{Err: &broker.Error{Kind: broker.ErrRateLimited}},
+			expectCode:  http.StatusTooManyRequests,
+			expectRetry: "1",
+		},
+		{
+			name:       "timeout",
+			err:        &search.UnavailableError{Err: &broker.Error{Kind: broker.ErrTimeout, Err: context.DeadlineExceeded}},
+			expectCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "malformed",
+			err:        &search.UnavailableError{Err: &broker.Error{Kind: broker.ErrMalformed}},
+			expectCode: http.StatusBadGateway,
+		},
+		{
+			name:        "unavailable",
+			err:         &search.UnavailableError{Err: &broker.Error{Kind: broker.ErrUnavailable, RetryAfter: 1500 * time.Millisecond}},
+			expectCode:  http.StatusServiceUnavailable,
+			expectRetry: "2",
+		},
+		{
+			name:       "unavailable without upstream error",
+			err:        search.ErrUnavailable,
+			expectCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:        "overloaded",
+			err:         search.ErrOverloaded,
+			expectCode:  http.StatusServiceUnavailable,
+			expectRetry: "1",
+		},
+		{
+			name:       "unexpected",
+			err:        errors.New("unexpected"),
+			expectCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			errorResponse(w, errors.Wrap(tc.err, "search"))
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected code: %d got: %d", tc.expectCode, w.Code)
+			}
+			if got := w.Header().Get("Retry-After"); got != tc.expectRetry {
+				t.Errorf("expected retry after: %q got: %q", tc.expectRetry, got)
+			}
+		})
+	}
+}
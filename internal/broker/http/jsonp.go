@@ -0,0 +1,70 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// callbackParam is a query param with JSONP callback.
+	callbackParam = "callback"
+	// maxCallbackLen limits length of JSONP callback.
+	maxCallbackLen = 64
+)
+
+// callbackRe matches JavaScript identifiers with optional
+// property access, like "jQuery123" or "widget.onPlaces".
+var callbackRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+func validCallback(callback string) bool {
+	return len(callback) <= maxCallbackLen && callbackRe.MatchString(callback)
+}
+
+// callbackError is a body of error response wrapped
+// into JSONP callback.
+type callbackError struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// writeError responds with status of the search error. JSONP
+// requests get 200 with status and error wrapped into callback
+// instead, since script tags do not run body of error responses.
+func (rs responder) writeError(w http.ResponseWriter, r *http.Request, err error) error {
+	callback := r.URL.Query().Get(callbackParam)
+	if !rs.jsonp || callback == "" || !validCallback(callback) {
+		return errorResponse(w, err)
+	}
+
+	setRetryAfter(w, err)
+	status := errorStatus(err)
+	body, err := json.Marshal(&callbackError{
+		Status: status,
+		Error:  http.StatusText(status),
+	})
+	if err != nil {
+		return errors.Wrap(err, "encode json")
+	}
+	body = append(body, '\n')
+
+	w.Header().Set("Content-Type", "application/javascript")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(wrapCallback(callback, body))
+	return errors.Wrap(err, "write response")
+}
+
+// wrapCallback wraps body into callback call. Leading comment
+// protects from content sniffing attacks on the response.
+func wrapCallback(callback string, body []byte) []byte {
+	result := make([]byte, 0, len(body)+len(callback)+8)
+	result = append(result, "/**/"...)
+	result = append(result, callback...)
+	result = append(result, '(')
+	result = append(result, body...)
+	result = append(result, ");\n"...)
+	return result
+}
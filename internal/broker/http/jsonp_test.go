@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/romanyx/places/internal/broker"
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestResponderWriteError(t *testing.T) {
+	tt := []struct {
+		name         string
+		jsonp        bool
+		query        string
+		err          error
+		expectCode   int
+		expectBody   string
+		expectRetry  string
+		expectScript bool
+	}{
+		{
+			name:       "plain",
+			jsonp:      true,
+			err:        broker.ErrBadRequest,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:         "jsonp",
+			jsonp:        true,
+			query:        "?callback=widget.onPlaces",
+			err:          search.ErrNotFound,
+			expectCode:   http.StatusOK,
+			expectBody:   "/**/widget.onPlaces({\"status\":404,\"error\":\"Not Found\"}\n);\n",
+			expectScript: true,
+		},
+		{
+			name:         "jsonp retry after",
+			jsonp:        true,
+			query:        "?callback=widget.onPlaces",
+			err:          &broker.Error{Kind: broker.ErrRateLimited, RetryAfter: time.Minute},
+			expectCode:   http.StatusOK,
+			expectBody:   "/**/widget.onPlaces({\"status\":429,\"error\":\"Too Many Requests\"}\n);\n",
+			expectRetry:  "60",
+			expectScript: true,
+		},
+		{
+			name:       "jsonp invalid callback",
+			jsonp:      true,
+			query:      "?callback=alert(1)",
+			err:        search.ErrNotFound,
+			expectCode: http.StatusNotFound,
+		},
+		{
+			name:       "jsonp disabled",
+			query:      "?callback=widget.onPlaces",
+			err:        search.ErrNotFound,
+			expectCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rs := responder{jsonp: tc.jsonp}
+			w := httptest.NewRecorder()
+			if err := rs.writeError(w, httptest.NewRequest(http.MethodGet, "/places"+tc.query, nil), tc.err); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected code: %d got: %d", tc.expectCode, w.Code)
+			}
+			if got := w.Body.String(); got != tc.expectBody {
+				t.Errorf("expected body: %q got: %q", tc.expectBody, got)
+			}
+			if got := w.Header().Get("Retry-After"); got != tc.expectRetry {
+				t.Errorf("expected retry after: %q got: %q", tc.expectRetry, got)
+			}
+			if script := w.Header().Get("Content-Type") == "application/javascript"; script != tc.expectScript {
+				t.Errorf("expected script: %t got: %t", tc.expectScript, script)
+			}
+		})
+	}
+}
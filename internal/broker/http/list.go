@@ -0,0 +1,91 @@
+package http
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/place"
+)
+
+const (
+	// maxLimit is a maximum number of places in response.
+	maxLimit = 100
+	// totalHeader carries number of places before pagination.
+	totalHeader = "X-Total-Count"
+)
+
+// listOptions filters and paginates search results.
+type listOptions struct {
+	types     map[string]struct{}
+	countries map[string]struct{}
+	limit     int
+	offset    int
+}
+
+// parseListOptions parses query params: types[] and
+// country[] filter places, limit and offset paginate them.
+func parseListOptions(f url.Values) (listOptions, error) {
+	opts := listOptions{
+		types:     set(f["types[]"], strings.ToLower),
+		countries: set(f["country[]"], strings.ToUpper),
+	}
+
+	var err error
+	if v := f.Get("limit"); v != "" {
+		opts.limit, err = strconv.Atoi(v)
+		if err != nil || opts.limit <= 0 || opts.limit > maxLimit {
+			return listOptions{}, errors.Errorf("invalid limit: %q", v)
+		}
+	}
+	if v := f.Get("offset"); v != "" {
+		opts.offset, err = strconv.Atoi(v)
+		if err != nil || opts.offset < 0 {
+			return listOptions{}, errors.Errorf("invalid offset: %q", v)
+		}
+	}
+
+	return opts, nil
+}
+
+// apply returns filtered places and their number before
+// pagination. Places of unknown type or country pass the
+// filters, since older cache entries do not have them.
+func (o listOptions) apply(places []place.Model) ([]place.Model, int) {
+	result := make([]place.Model, 0, len(places))
+	for _, m := range places {
+		if !matches(o.types, strings.ToLower(m.Type)) || !matches(o.countries, strings.ToUpper(m.CountryCode)) {
+			continue
+		}
+		result = append(result, m)
+	}
+
+	total := len(result)
+	if o.offset >= total {
+		return result[:0], total
+	}
+	result = result[o.offset:]
+	if o.limit > 0 && o.limit < len(result) {
+		result = result[:o.limit]
+	}
+
+	return result, total
+}
+
+func matches(allowed map[string]struct{}, v string) bool {
+	if len(allowed) == 0 || v == "" {
+		return true
+	}
+	_, ok := allowed[v]
+	return ok
+}
+
+func set(values []string, normalize func(string) string) map[string]struct{} {
+	result := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		result[normalize(v)] = struct{}{}
+	}
+	return result
+}
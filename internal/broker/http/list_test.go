@@ -0,0 +1,94 @@
+package http
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/romanyx/places/internal/place"
+)
+
+func TestListOptionsApply(t *testing.T) {
+	places := []place.Model{
+		{Slug: "MOW", Type: "city", CountryCode: "RU"},
+		{Slug: "SVO", Type: "airport", CountryCode: "RU"},
+		{Slug: "MLW", Type: "airport", CountryCode: "LR"},
+		{Slug: "OLD"},
+	}
+
+	tt := []struct {
+		name        string
+		query       string
+		expect      []string
+		expectTotal int
+		expectErr   bool
+	}{
+		{
+			name:        "all",
+			expect:      []string{"MOW", "SVO", "MLW", "OLD"},
+			expectTotal: 4,
+		},
+		{
+			name:        "type",
+			query:       "types[]=airport",
+			expect:      []string{"SVO", "MLW", "OLD"},
+			expectTotal: 3,
+		},
+		{
+			name:        "country",
+			query:       "country[]=ru",
+			expect:      []string{"MOW", "SVO", "OLD"},
+			expectTotal: 3,
+		},
+		{
+			name:        "page",
+			query:       "limit=2&offset=1",
+			expect:      []string{"SVO", "MLW"},
+			expectTotal: 4,
+		},
+		{
+			name:        "offset out of range",
+			query:       "offset=10",
+			expect:      []string{},
+			expectTotal: 4,
+		},
+		{
+			name:      "invalid limit",
+			query:     "limit=0",
+			expectErr: true,
+		},
+		{
+			name:      "invalid offset",
+			query:     "offset=-1",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, _ := url.ParseQuery(tc.query)
+			opts, err := parseListOptions(f)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, total := opts.apply(places)
+			slugs := make([]string, len(got))
+			for i := range got {
+				slugs[i] = got[i].Slug
+			}
+			if !reflect.DeepEqual(tc.expect, slugs) || total != tc.expectTotal {
+				t.Errorf("expected: %v %d got: %v %d", tc.expect, tc.expectTotal, slugs, total)
+			}
+		})
+	}
+}
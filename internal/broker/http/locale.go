@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/romanyx/places/internal/tenant"
+)
+
+// requestLocale returns locale of the request from its
+// Accept-Language header, or default locale of the tenant.
+func requestLocale(r *http.Request) string {
+	if locale := acceptLanguage(r.Header.Get("Accept-Language")); locale != "" {
+		return locale
+	}
+	if t := tenant.FromContext(r.Context()); t != nil {
+		return t.Locale
+	}
+	return ""
+}
+
+// acceptLanguage returns primary language of the most
+// preferred tag from Accept-Language header value, or
+// empty string when header has no acceptable languages.
+func acceptLanguage(header string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.TrimSpace(fields[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if !strings.HasPrefix(f, "q=") {
+				continue
+			}
+			v, err := strconv.ParseFloat(f[2:], 64)
+			if err != nil {
+				v = 0
+			}
+			q = v
+		}
+		if q <= 0 {
+			continue
+		}
+
+		if i := strings.IndexByte(lang, '-'); i >= 0 {
+			lang = lang[:i]
+		}
+		tags = append(tags, tag{lang: strings.ToLower(lang), q: q})
+	}
+
+	if len(tags) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+	return tags[0].lang
+}
@@ -0,0 +1,23 @@
+package http
+
+import "testing"
+
+func Test_acceptLanguage(t *testing.T) {
+	tt := []struct {
+		header string
+		expect string
+	}{
+		{header: "", expect: ""},
+		{header: "ru", expect: "ru"},
+		{header: "en-US,en;q=0.9", expect: "en"},
+		{header: "uk;q=0.5, ru-RU;q=0.8, *;q=0.1", expect: "ru"},
+		{header: "de;q=0, fr", expect: "fr"},
+		{header: "*", expect: ""},
+	}
+
+	for _, tc := range tt {
+		if got := acceptLanguage(tc.header); got != tc.expect {
+			t.Errorf("%q: expected: %q got: %q", tc.header, tc.expect, got)
+		}
+	}
+}
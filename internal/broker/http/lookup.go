@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+type lookupHandler struct {
+	Searcher
+	responder
+}
+
+func newLookupHandler(searcher Searcher, rs responder) http.Handler {
+	lookupHandler := lookupHandler{
+		Searcher:  searcher,
+		responder: rs,
+	}
+
+	h := httpHandler{lookupHandler}
+	return h
+}
+
+// Handle finds place by slug from the path /places/{slug}.
+func (h lookupHandler) Handle(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return methodNotAllowedResponse(w)
+	}
+
+	slug := strings.TrimPrefix(r.URL.Path, "/places/")
+	if slug == "" || strings.Contains(slug, "/") {
+		return notFoundResponse(w)
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = requestLocale(r)
+	}
+
+	ctx, meta := metaContext(r.Context())
+	model, err := h.Lookup(ctx, slug, locale)
+	if err != nil {
+		return h.writeError(w, r, err)
+	}
+
+	return h.write(w, r, meta, &model)
+}
@@ -0,0 +1,223 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.opencensus.io/trace"
+
+	"github.com/romanyx/places/internal/recovery"
+)
+
+const (
+	// maxRequestID is a maximum length of request id
+	// accepted from client.
+	maxRequestID = 64
+)
+
+// Middleware wraps handler with cross-cutting behavior.
+type Middleware func(http.Handler) http.Handler
+
+// WithMiddleware appends middleware to the chain of server
+// handler, the first one is the outermost. Chain runs inside
+// tracing and recovery, so span of the request is available,
+// and outside of access log and CORS.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// Chain wraps handler with middleware, the first
+// one is the outermost.
+func Chain(h http.Handler, mw ...Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// panicResponse is a body of response to the request
+// which handler panicked.
+type panicResponse struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// Recovery recovers panics of the next handler, reports them
+// and responds with JSON 500 unless response was started.
+// Servers apply it by default.
+func Recovery() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := statusRecorder{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				// Server aborts response without logging.
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				recovery.Report(r.Context(), "http", v)
+				if rec.status != 0 {
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(&panicResponse{
+					Status: http.StatusInternalServerError,
+					Error:  http.StatusText(http.StatusInternalServerError),
+				})
+			}()
+
+			next.ServeHTTP(&rec, r)
+		})
+	}
+}
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns id of the request,
+// or empty string when there is no id.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID takes request id from header or generates a new
+// one when header is missing or invalid. Id is stored in
+// context, added to span and returned in the same header.
+func RequestID(header string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(header, id)
+			trace.FromContext(r.Context()).AddAttributes(trace.StringAttribute("request_id", id))
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// containsIP reports whether IP address s belongs
+// to one of networks.
+func containsIP(nets []*net.IPNet, s string) bool {
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RealIP replaces remote address of requests which came from
+// trusted proxies with the client address. The client is the
+// rightmost address in X-Forwarded-For which is not a trusted
+// proxy, X-Real-IP is used when X-Forwarded-For is missing.
+func RealIP(trusted []*net.IPNet) Middleware {
+	isTrusted := func(s string) bool {
+		return containsIP(trusted, s)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isTrusted(remoteIP(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var ip string
+			forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(forwarded) - 1; i >= 0; i-- {
+				addr := strings.TrimSpace(forwarded[i])
+				if addr == "" {
+					continue
+				}
+				ip = addr
+				if !isTrusted(addr) {
+					break
+				}
+			}
+			if ip == "" {
+				ip = strings.TrimSpace(r.Header.Get("X-Real-IP"))
+			}
+
+			if net.ParseIP(ip) != nil {
+				r2 := r.Clone(r.Context())
+				r2.RemoteAddr = ip
+				r = r2
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BodyLimit responds with 413 to requests which body is
+// larger than n bytes, reading of longer bodies fails.
+func BodyLimit(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				entityTooLargeResponse(w)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SecurityHeaders sets headers which forbid browsers to sniff
+// content type, to frame responses and to send referrer.
+// Strict-Transport-Security is set for TLS requests.
+func SecurityHeaders() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Referrer-Policy", "no-referrer")
+			h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+			if r.TLS != nil {
+				h.Set("Strict-Transport-Security", "max-age=31536000")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
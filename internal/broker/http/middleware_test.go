@@ -0,0 +1,347 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), mw("first"), mw("second"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	expect := []string{"first", "second", "handler"}
+	if !reflect.DeepEqual(expect, order) {
+		t.Errorf("expected: %v got: %v", expect, order)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	tt := []struct {
+		name       string
+		handler    http.HandlerFunc
+		expectCode int
+		expectBody string
+	}{
+		{
+			name: "panic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			expectCode: http.StatusInternalServerError,
+			expectBody: `{"status":500,"error":"Internal Server Error"}`,
+		},
+		{
+			name: "panic after response started",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("partial"))
+				panic("boom")
+			},
+			expectCode: http.StatusOK,
+			expectBody: "partial",
+		},
+		{
+			name:       "no panic",
+			handler:    func(w http.ResponseWriter, r *http.Request) {},
+			expectCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			Recovery()(tc.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected code: %d got: %d", tc.expectCode, w.Code)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tc.expectBody {
+				t.Errorf("expected: %s got: %s", tc.expectBody, got)
+			}
+		})
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("expected: %v got: %v", http.ErrAbortHandler, v)
+		}
+	}()
+
+	h := Recovery()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestServerRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer("", searcherFunc(func(ctx context.Context, p search.Params) ([]place.Model, error) {
+		panic("boom")
+	}),
+		WithCORS(CORS{AllowedOrigins: []string{"https://example.com"}}),
+		WithAccessLog(AccessLog{Output: &buf, SampleRate: 1}),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/places?term=Moscow", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected code: %d got: %d", http.StatusInternalServerError, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Errorf("expected allowed origin got: %q", got)
+	}
+	var e accessEntry
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("decode access log: %v", err)
+	}
+	if e.Status != http.StatusInternalServerError {
+		t.Errorf("expected logged status: %d got: %d", http.StatusInternalServerError, e.Status)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tt := []struct {
+		name     string
+		id       string
+		expectID func(string) bool
+	}{
+		{
+			name:     "reuse",
+			id:       "abc-123",
+			expectID: func(id string) bool { return id == "abc-123" },
+		},
+		{
+			name:     "generate",
+			expectID: func(id string) bool { return len(id) == 32 },
+		},
+		{
+			name:     "invalid",
+			id:       "bad id",
+			expectID: func(id string) bool { return len(id) == 32 },
+		},
+		{
+			name:     "too long",
+			id:       strings.Repeat("a", maxRequestID+1),
+			expectID: func(id string) bool { return len(id) == 32 },
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ctxID string
+			h := RequestID("X-Request-ID")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = RequestIDFromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.id != "" {
+				r.Header.Set("X-Request-ID", tc.id)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			got := w.Header().Get("X-Request-ID")
+			if !tc.expectID(got) {
+				t.Errorf("unexpected id: %q", got)
+			}
+			if ctxID != got {
+				t.Errorf("expected context id: %q got: %q", got, ctxID)
+			}
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	_, trusted, _ := net.ParseCIDR("10.0.0.0/8")
+
+	tt := []struct {
+		name      string
+		remote    string
+		forwarded string
+		realIP    string
+		expect    string
+	}{
+		{
+			name:      "untrusted remote",
+			remote:    "192.0.2.1:1234",
+			forwarded: "198.51.100.1",
+			expect:    "192.0.2.1:1234",
+		},
+		{
+			name:      "trusted remote",
+			remote:    "10.0.0.1:1234",
+			forwarded: "198.51.100.1",
+			expect:    "198.51.100.1",
+		},
+		{
+			name:      "rightmost untrusted",
+			remote:    "10.0.0.1:1234",
+			forwarded: "203.0.113.1, 198.51.100.1, 10.0.0.2",
+			expect:    "198.51.100.1",
+		},
+		{
+			name:   "real ip",
+			remote: "10.0.0.1:1234",
+			realIP: "198.51.100.1",
+			expect: "198.51.100.1",
+		},
+		{
+			name:      "invalid",
+			remote:    "10.0.0.1:1234",
+			forwarded: "unknown",
+			expect:    "10.0.0.1:1234",
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			h := RealIP([]*net.IPNet{trusted})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remote
+			if tc.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.realIP != "" {
+				r.Header.Set("X-Real-IP", tc.realIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tc.expect {
+				t.Errorf("expected: %q got: %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		unknown    bool
+		expectCode int
+	}{
+		{
+			name:       "within limit",
+			body:       "1234",
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "content length over limit",
+			body:       "123456",
+			expectCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "read over limit",
+			body:       "123456",
+			unknown:    true,
+			expectCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := BodyLimit(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := ioutil.ReadAll(r.Body); err != nil {
+					entityTooLargeResponse(w)
+				}
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			if tc.unknown {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected: %d got: %d", tc.expectCode, w.Code)
+			}
+		})
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	tt := []struct {
+		name       string
+		tls        bool
+		expectHSTS bool
+	}{
+		{
+			name: "plain",
+		},
+		{
+			name:       "tls",
+			tls:        true,
+			expectHSTS: true,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := SecurityHeaders()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("expected nosniff got: %q", got)
+			}
+			if got := w.Header().Get("X-Frame-Options"); got != "DENY" {
+				t.Errorf("expected DENY got: %q", got)
+			}
+			if hsts := w.Header().Get("Strict-Transport-Security") != ""; hsts != tc.expectHSTS {
+				t.Errorf("expected hsts: %t got: %t", tc.expectHSTS, hsts)
+			}
+		})
+	}
+}
@@ -41,7 +41,7 @@ func (s *SearcherWithLog) Search(ctx context.Context, p search.Params) ([]place.
 	})
 
 	defer func() {
-		if err != nil && errors.Cause(err) != search.ErrUnavailable {
+		if err != nil && !expectedError(err) {
 			log.Error(errors.Wrap(err, "search error"), map[string]interface{}{
 				"trace_id": spanCtx.TraceID,
 				"term":     p.Term,
@@ -65,6 +65,41 @@ func (s *SearcherWithLog) Search(ctx context.Context, p search.Params) ([]place.
 	return places, err
 }
 
+// Lookup decoraters lookup method.
+func (s *SearcherWithLog) Lookup(ctx context.Context, slug, locale string) (place.Model, error) {
+	var model place.Model
+	var err error
+	start := time.Now()
+	spanCtx := trace.FromContext(ctx).SpanContext()
+	log.Debug("lookup processing", map[string]interface{}{
+		"trace_id": spanCtx.TraceID,
+		"slug":     slug,
+		"language": locale,
+	})
+
+	defer func() {
+		if err != nil && !expectedError(err) {
+			log.Error(errors.Wrap(err, "lookup error"), map[string]interface{}{
+				"trace_id": spanCtx.TraceID,
+				"slug":     slug,
+				"language": locale,
+				"elapsed":  time.Since(start),
+			})
+			return
+		}
+
+		log.Debug("lookup processed", map[string]interface{}{
+			"trace_id": spanCtx.TraceID,
+			"slug":     slug,
+			"language": locale,
+			"elapsed":  time.Since(start),
+		})
+	}()
+
+	model, err = s.base.Lookup(ctx, slug, locale)
+	return model, err
+}
+
 // SearcherWithTrace decorates searcher with trace.
 type SearcherWithTrace struct {
 	base Searcher
@@ -91,7 +126,7 @@ func (s *SearcherWithTrace) Search(ctx context.Context, p search.Params) ([]plac
 	var places []place.Model
 
 	defer func() {
-		if err != nil && errors.Cause(err) != search.ErrUnavailable {
+		if err != nil && !expectedError(err) {
 			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
 			span.End()
 			return
@@ -104,3 +139,36 @@ func (s *SearcherWithTrace) Search(ctx context.Context, p search.Params) ([]plac
 	places, err = s.base.Search(ctx, p)
 	return places, err
 }
+
+// Lookup decoraters lookup method.
+func (s *SearcherWithTrace) Lookup(ctx context.Context, slug, locale string) (place.Model, error) {
+	_, span := trace.StartSpan(ctx, "search.lookup")
+	span.AddAttributes(
+		trace.StringAttribute("slug", slug),
+		trace.StringAttribute("locale", locale),
+	)
+	var err error
+	var model place.Model
+
+	defer func() {
+		if err != nil && !expectedError(err) {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			span.End()
+			return
+		}
+
+		span.SetStatus(trace.Status{Code: trace.StatusCodeOK, Message: "lookup successed"})
+		span.End()
+	}()
+
+	model, err = s.base.Lookup(ctx, slug, locale)
+	return model, err
+}
+
+// expectedError reports whether err is a regular
+// outcome of search, which is not worth an alert.
+func expectedError(err error) bool {
+	return errors.Is(err, search.ErrUnavailable) ||
+		errors.Is(err, search.ErrOverloaded) ||
+		errors.Is(err, search.ErrNotFound)
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/romanyx/places/internal/tenant"
+)
+
+// TenantViews contains views of server requests by tenant.
+var TenantViews = []*view.View{
+	{
+		Name:        "places/http/server/response_count_by_tenant",
+		Description: "Server response count by tenant and status code",
+		Measure:     ochttp.ServerLatency,
+		TagKeys:     []tag.Key{tenant.Key, ochttp.StatusCode},
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "places/http/server/latency_by_tenant",
+		Description: "Latency distribution of HTTP requests by tenant",
+		Measure:     ochttp.ServerLatency,
+		TagKeys:     []tag.Key{tenant.Key},
+		Aggregation: ochttp.DefaultLatencyDistribution,
+	},
+}
+
+// tenantHandler resolves tenant of the request by header,
+// which names tenant explicitly, or by host and passes it
+// to the next handler in context. Header is honored only
+// for trusted requests, since otherwise any client could
+// name any tenant. Requests which match no tenant are
+// served for the default one.
+type tenantHandler struct {
+	next     http.Handler
+	registry *tenant.Registry
+	header   string
+	trusted  func(*http.Request) bool
+}
+
+func newTenantHandler(next http.Handler, registry *tenant.Registry, header string, trusted func(*http.Request) bool) http.Handler {
+	h := tenantHandler{
+		next:     next,
+		registry: registry,
+		header:   header,
+		trusted:  trusted,
+	}
+
+	return &h
+}
+
+// ServeHTTP implements http.Handler. Responds with 400
+// when header names unknown tenant.
+func (h *tenantHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var t *tenant.Tenant
+	var ok bool
+	if id := r.Header.Get(h.header); id != "" && h.trusted(r) {
+		if t, ok = h.registry.ByID(id); !ok {
+			badRequestResponse(w)
+			return
+		}
+	} else {
+		t, ok = h.registry.ByHost(r.Host)
+	}
+
+	if ok {
+		r = r.WithContext(tenant.NewContext(r.Context(), t))
+	}
+	h.next.ServeHTTP(w, r)
+}
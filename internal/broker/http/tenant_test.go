@@ -0,0 +1,117 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/romanyx/places/internal/tenant"
+)
+
+func TestTenantHandler(t *testing.T) {
+	registry, err := tenant.NewRegistry([]tenant.Tenant{
+		{ID: "acme", Hosts: []string{"acme.example.com"}, Locale: "ru"},
+		{ID: "globex", Hosts: []string{"globex.example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("new registry: %v", err)
+	}
+
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+
+	tt := []struct {
+		name         string
+		host         string
+		remote       string
+		header       string
+		language     string
+		expectCode   int
+		expectTenant string
+		expectLocale string
+	}{
+		{
+			name:         "by host",
+			host:         "acme.example.com",
+			expectCode:   http.StatusOK,
+			expectTenant: "acme",
+			expectLocale: "ru",
+		},
+		{
+			name:         "header overrides host",
+			host:         "acme.example.com",
+			remote:       "10.0.0.1:1234",
+			header:       "globex",
+			expectCode:   http.StatusOK,
+			expectTenant: "globex",
+		},
+		{
+			name:         "header of untrusted client",
+			host:         "acme.example.com",
+			header:       "globex",
+			expectCode:   http.StatusOK,
+			expectTenant: "acme",
+			expectLocale: "ru",
+		},
+		{
+			name:         "accept language overrides tenant locale",
+			host:         "acme.example.com",
+			language:     "en-US",
+			expectCode:   http.StatusOK,
+			expectTenant: "acme",
+			expectLocale: "en",
+		},
+		{
+			name:       "default tenant",
+			host:       "example.com",
+			expectCode: http.StatusOK,
+		},
+		{
+			name:       "unknown tenant",
+			remote:     "10.0.0.1:1234",
+			header:     "initech",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotTenant, gotLocale string
+			h := newTenantHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if t := tenant.FromContext(r.Context()); t != nil {
+					gotTenant = t.ID
+				}
+				gotLocale = requestLocale(r)
+			}), registry, "X-Places-Tenant", func(r *http.Request) bool {
+				return containsIP([]*net.IPNet{proxies}, remoteIP(r))
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/places", nil)
+			r.Host = tc.host
+			if tc.remote != "" {
+				r.RemoteAddr = tc.remote
+			}
+			if tc.header != "" {
+				r.Header.Set("X-Places-Tenant", tc.header)
+			}
+			if tc.language != "" {
+				r.Header.Set("Accept-Language", tc.language)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.expectCode {
+				t.Errorf("expected code: %d got: %d", tc.expectCode, w.Code)
+			}
+			if gotTenant != tc.expectTenant {
+				t.Errorf("expected tenant: %q got: %q", tc.expectTenant, gotTenant)
+			}
+			if gotLocale != tc.expectLocale {
+				t.Errorf("expected locale: %q got: %q", tc.expectLocale, gotLocale)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/log"
+)
+
+// TLS represents TLS configuration of the server.
+type TLS struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables verification of client
+	// certificates signed by CAs from the file.
+	ClientCAFile string
+	// RequireClientCert rejects clients without certificate,
+	// otherwise they are allowed and only presented
+	// certificates are verified.
+	RequireClientCert bool
+	// MinVersion is a minimum TLS version, one of 1.0, 1.1,
+	// 1.2 and 1.3, default is 1.2.
+	MinVersion string
+	// CipherSuites are names of cipher suites allowed for
+	// TLS 1.2 and lower, default suites are used when empty.
+	CipherSuites []string
+}
+
+// certCheckInterval is a minimum interval between checks
+// of certificate files for changes.
+const certCheckInterval = 10 * time.Second
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTLSConfig returns TLS config for the server. Certificate
+// and key files are reloaded when they change, so certificates
+// may be renewed without restart.
+func NewTLSConfig(cfg TLS) (*tls.Config, error) {
+	if cfg.RequireClientCert && cfg.ClientCAFile == "" {
+		return nil, errors.New("client certificate required without client CA")
+	}
+	if cfg.MinVersion == "" {
+		cfg.MinVersion = "1.2"
+	}
+	minVersion, ok := tlsVersions[cfg.MinVersion]
+	if !ok {
+		return nil, errors.Errorf("unknown TLS version %s", cfg.MinVersion)
+	}
+
+	suites, err := cipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, errors.Wrap(err, "cipher suites")
+	}
+
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile, certCheckInterval)
+	if err != nil {
+		return nil, errors.Wrap(err, "load certificate")
+	}
+
+	c := tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     minVersion,
+		CipherSuites:   suites,
+	}
+
+	if cfg.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read client CA")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, errors.Errorf("no certificates in %s", cfg.ClientCAFile)
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.RequireClientCert {
+			c.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return &c, nil
+}
+
+// cipherSuites returns ids of secure cipher suites by names.
+// HTTP/2 requires TLS_ECDHE_*_WITH_AES_128_GCM_SHA256 suite,
+// so names without it are rejected.
+func cipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	var http2 bool
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, errors.Errorf("unknown or insecure cipher suite %s", name)
+		}
+		if id == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 || id == tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+			http2 = true
+		}
+		ids = append(ids, id)
+	}
+	if !http2 {
+		return nil, errors.New("cipher suites have neither TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 nor TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 required by HTTP/2")
+	}
+	return ids, nil
+}
+
+// certReloader loads certificate again when modification
+// time of certificate or key file changes. Files are checked
+// at most once per interval, so handshakes do not stat them.
+// Previous certificate is kept when new one fails to load,
+// load is retried on the next change.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	r := certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		checked:  time.Now(),
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if r.due() && r.changed() {
+		if err := r.load(); err != nil {
+			log.Warn(errors.Wrap(err, "reload certificate"), map[string]interface{}{
+				"cert": r.certFile,
+			})
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// due reports whether interval passed since files
+// were checked last time.
+func (r *certReloader) due() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.checked) < r.interval {
+		return false
+	}
+	r.checked = now
+	return true
+}
+
+func (r *certReloader) changed() bool {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !modTime.Equal(r.modTime)
+}
+
+func (r *certReloader) load() error {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modTime = modTime
+	if err != nil {
+		return errors.Wrap(err, "load key pair")
+	}
+	r.cert = &cert
+	return nil
+}
+
+// lastModified returns the latest modification
+// time of certificate and key files.
+func (r *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "stat")
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,387 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", &ca)
+	certFile, keyFile := server.write(t, dir, "server")
+	caFile, _ := ca.write(t, dir, "ca")
+	garbage := filepath.Join(dir, "garbage")
+	writeFile(t, garbage, []byte("garbage"))
+
+	tt := []struct {
+		name             string
+		cfg              TLS
+		expectErr        bool
+		expectVersion    uint16
+		expectClientAuth tls.ClientAuthType
+	}{
+		{
+			name:          "default",
+			cfg:           TLS{CertFile: certFile, KeyFile: keyFile},
+			expectVersion: tls.VersionTLS12,
+		},
+		{
+			name:          "min version",
+			cfg:           TLS{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"},
+			expectVersion: tls.VersionTLS13,
+		},
+		{
+			name:      "unknown version",
+			cfg:       TLS{CertFile: certFile, KeyFile: keyFile, MinVersion: "2.0"},
+			expectErr: true,
+		},
+		{
+			name: "cipher suites",
+			cfg: TLS{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{
+				"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+			}},
+			expectVersion: tls.VersionTLS12,
+		},
+		{
+			name: "no HTTP/2 cipher suite",
+			cfg: TLS{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{
+				"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+			}},
+			expectErr: true,
+		},
+		{
+			name: "insecure cipher suite",
+			cfg: TLS{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{
+				"TLS_RSA_WITH_RC4_128_SHA",
+			}},
+			expectErr: true,
+		},
+		{
+			name:      "missing certificate",
+			cfg:       TLS{CertFile: filepath.Join(dir, "missing"), KeyFile: keyFile},
+			expectErr: true,
+		},
+		{
+			name:             "client certificate verified",
+			cfg:              TLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile},
+			expectVersion:    tls.VersionTLS12,
+			expectClientAuth: tls.VerifyClientCertIfGiven,
+		},
+		{
+			name:             "client certificate required",
+			cfg:              TLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, RequireClientCert: true},
+			expectVersion:    tls.VersionTLS12,
+			expectClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name:      "client certificate required without CA",
+			cfg:       TLS{CertFile: certFile, KeyFile: keyFile, RequireClientCert: true},
+			expectErr: true,
+		},
+		{
+			name:      "invalid client CA",
+			cfg:       TLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: garbage},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := NewTLSConfig(tc.cfg)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if c.MinVersion != tc.expectVersion {
+				t.Errorf("expected version: %x got: %x", tc.expectVersion, c.MinVersion)
+			}
+			if c.ClientAuth != tc.expectClientAuth {
+				t.Errorf("expected client auth: %v got: %v", tc.expectClientAuth, c.ClientAuth)
+			}
+		})
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	first := newTestCert(t, "first", &ca)
+	certFile, keyFile := first.write(t, dir, "server")
+
+	r, err := newCertReloader(certFile, keyFile, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := &tls.Config{GetCertificate: r.GetCertificate}
+	expectCert(t, c, first)
+
+	// Modification time is moved forward, since files
+	// may be rewritten within time resolution of fs.
+	second := newTestCert(t, "second", &ca)
+	second.write(t, dir, "server")
+	touch(t, time.Now().Add(time.Minute), certFile, keyFile)
+	expectCert(t, c, second)
+
+	writeFile(t, certFile, []byte("garbage"))
+	touch(t, time.Now().Add(2*time.Minute), certFile)
+	expectCert(t, c, second)
+
+	// Files are not checked again within interval.
+	r.interval = time.Hour
+	r.checked = time.Now()
+	third := newTestCert(t, "third", &ca)
+	third.write(t, dir, "server")
+	touch(t, time.Now().Add(3*time.Minute), certFile, keyFile)
+	expectCert(t, c, second)
+
+	r.checked = time.Now().Add(-time.Hour)
+	expectCert(t, c, third)
+}
+
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", &ca)
+	client := newTestCert(t, "client", &ca)
+	certFile, keyFile := server.write(t, dir, "server")
+	caFile, _ := ca.write(t, dir, "ca")
+
+	c, err := NewTLSConfig(TLS{
+		CertFile:          certFile,
+		KeyFile:           keyFile,
+		ClientCAFile:      caFile,
+		RequireClientCert: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := NewServer("", testSearcher(), WithTLSConfig(c))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go s.ServeTLS(ln, "", "")
+	defer s.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientCert, err := tls.X509KeyPair(client.certPEM, client.keyPEM)
+	if err != nil {
+		t.Fatalf("client key pair: %v", err)
+	}
+
+	tt := []struct {
+		name      string
+		certs     []tls.Certificate
+		expectErr bool
+	}{
+		{
+			name:  "client certificate",
+			certs: []tls.Certificate{clientCert},
+		},
+		{
+			name:      "no client certificate",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      roots,
+					Certificates: tc.certs,
+				},
+				ForceAttemptHTTP2: true,
+			}
+			defer transport.CloseIdleConnections()
+
+			resp, err := (&http.Client{Transport: &transport}).Get("https://" + ln.Addr().String() + "/places?term=Moscow")
+			if tc.expectErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.ProtoMajor != 2 {
+				t.Errorf("expected HTTP/2 got: %s", resp.Proto)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected code: %d got: %d", http.StatusOK, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestServerH2C(t *testing.T) {
+	s := httptest.NewServer(NewServer("", testSearcher(), WithH2C()).Handler)
+	defer s.Close()
+
+	transport := http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	resp, err := (&http.Client{Transport: &transport}).Get(s.URL + "/places?term=Moscow")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2 got: %s", resp.Proto)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected code: %d got: %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func testSearcher() Searcher {
+	return searcherFunc(func(ctx context.Context, p search.Params) ([]place.Model, error) {
+		return []place.Model{{Slug: p.Term}}, nil
+	})
+}
+
+// testCert is a certificate generated for tests, it is
+// self-signed when parent is not set.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert) testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("generate serial: %v", err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signerKey := &tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	return testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// write writes certificate and key files named
+// by name to dir and returns their paths.
+func (c testCert) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	writeFile(t, certFile, c.certPEM)
+	writeFile(t, keyFile, c.keyPEM)
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(name, data, 0600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func touch(t *testing.T, modTime time.Time, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatalf("touch %s: %v", name, err)
+		}
+	}
+}
+
+func expectCert(t *testing.T, c *tls.Config, expect testCert) {
+	t.Helper()
+
+	got, err := c.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("get certificate: %v", err)
+	}
+	if !bytes.Equal(got.Certificate[0], expect.cert.Raw) {
+		t.Errorf("expected certificate: %s got other one", expect.cert.Subject.CommonName)
+	}
+}
@@ -0,0 +1,241 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/recovery"
+	"github.com/romanyx/places/internal/search"
+)
+
+const (
+	// typeaheadDebounce is a default pause after the last
+	// term before search starts.
+	typeaheadDebounce = 150 * time.Millisecond
+	// typeaheadIdle closes connections which send
+	// no terms for the period.
+	typeaheadIdle = 5 * time.Minute
+	// typeaheadWriteWait is a time allowed to write
+	// a result to the client.
+	typeaheadWriteWait = 10 * time.Second
+	// maxTypeaheadMessage is a maximum size of a message
+	// with params sent by client.
+	maxTypeaheadMessage = 4 << 10
+)
+
+// typeaheadResult represents result of the search for the
+// latest term. Places are set when status is 200, error
+// otherwise.
+type typeaheadResult struct {
+	Term      string        `json:"term"`
+	Places    []place.Model `json:"places"`
+	Status    int           `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Corrected string        `json:"corrected,omitempty"`
+}
+
+type typeaheadHandler struct {
+	Searcher
+	debounce time.Duration
+	upgrader websocket.Upgrader
+}
+
+func newTypeaheadHandler(searcher Searcher, debounce time.Duration, cors *CORS) http.Handler {
+	typeaheadHandler := typeaheadHandler{
+		Searcher: searcher,
+		debounce: debounce,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin(cors),
+		},
+	}
+
+	h := httpHandler{typeaheadHandler}
+	return h
+}
+
+// Handle upgrades connection to WebSocket, reads params of
+// successive terms from it and writes results back. Search
+// starts when client pauses typing for debounce, search of
+// the previous term is cancelled when the next one arrives,
+// so only result of the latest term is written.
+func (h typeaheadHandler) Handle(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return methodNotAllowedResponse(w)
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrader responds with error itself.
+		return nil
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	params := make(chan search.Params)
+	go readParams(ctx, conn, requestLocale(r), params)
+
+	if err := h.serve(ctx, conn, params); err != nil {
+		return errors.Wrap(err, "serve typeahead")
+	}
+	return nil
+}
+
+func (h typeaheadHandler) serve(ctx context.Context, conn *websocket.Conn, params <-chan search.Params) error {
+	timer := time.NewTimer(h.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	var (
+		latest search.Params
+		seq    int
+		// cancelSearch cancels search in flight.
+		cancelSearch context.CancelFunc = func() {}
+		results                         = make(chan typeaheadSearch)
+	)
+	defer func() {
+		cancelSearch()
+	}()
+
+	for {
+		select {
+		case p, ok := <-params:
+			if !ok {
+				return nil
+			}
+			// Result of the previous term is outdated.
+			cancelSearch()
+			seq++
+			latest = p
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(h.debounce)
+		case <-timer.C:
+			cancelSearch = h.start(ctx, seq, latest, results)
+		case s := <-results:
+			if s.seq != seq {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(typeaheadWriteWait))
+			if err := conn.WriteJSON(&s.result); err != nil {
+				return errors.Wrap(err, "write result")
+			}
+		}
+	}
+}
+
+// typeaheadSearch is a result of search started
+// for the term with sequence number seq.
+type typeaheadSearch struct {
+	seq    int
+	result typeaheadResult
+}
+
+// start starts search in background, returned
+// function cancels it.
+func (h typeaheadHandler) start(ctx context.Context, seq int, p search.Params, results chan<- typeaheadSearch) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	go h.search(ctx, seq, p, results)
+	return cancel
+}
+
+func (h typeaheadHandler) search(ctx context.Context, seq int, p search.Params, results chan<- typeaheadSearch) {
+	result := h.result(ctx, p)
+
+	select {
+	case results <- typeaheadSearch{seq: seq, result: result}:
+	case <-ctx.Done():
+	}
+}
+
+// result searches places of the term, panic of the
+// search is reported as 500 result.
+func (h typeaheadHandler) result(ctx context.Context, p search.Params) (result typeaheadResult) {
+	defer func() {
+		if v := recover(); v != nil {
+			recovery.Report(ctx, "typeahead", v)
+			result = typeaheadResult{
+				Term:   p.Term,
+				Status: http.StatusInternalServerError,
+				Error:  http.StatusText(http.StatusInternalServerError),
+			}
+		}
+	}()
+
+	ctx, meta := search.WithMeta(ctx)
+	places, err := h.Search(ctx, p)
+
+	result = typeaheadResult{
+		Term:      p.Term,
+		Places:    places,
+		Status:    http.StatusOK,
+		Corrected: meta.Corrected,
+	}
+	if err != nil {
+		result.Places = nil
+		result.Status = errorStatus(err)
+		result.Error = http.StatusText(result.Status)
+	}
+	return result
+}
+
+// readParams reads params from conn until it is closed or
+// idle for too long. Params without locale inherit it from
+// the handshake request.
+func readParams(ctx context.Context, conn *websocket.Conn, locale string, params chan<- search.Params) {
+	defer close(params)
+	defer recovery.Recover(ctx, "typeahead_read")
+
+	conn.SetReadLimit(maxTypeaheadMessage)
+	for {
+		conn.SetReadDeadline(time.Now().Add(typeaheadIdle))
+		var p search.Params
+		if err := conn.ReadJSON(&p); err != nil {
+			return
+		}
+		if p.Locale == "" {
+			p.Locale = locale
+		}
+
+		select {
+		case params <- p:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkOrigin allows origins allowed by CORS, same
+// origin only is allowed when CORS is not configured.
+func checkOrigin(cors *CORS) func(*http.Request) bool {
+	if cors == nil {
+		return nil
+	}
+
+	origins := make(map[string]struct{}, len(cors.AllowedOrigins))
+	for _, origin := range cors.AllowedOrigins {
+		if origin == "*" {
+			return func(*http.Request) bool { return true }
+		}
+		origins[origin] = struct{}{}
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		_, ok := origins[origin]
+		return ok
+	}
+}
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/romanyx/places/internal/broker"
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestTypeaheadHandler(t *testing.T) {
+	cancelled := make(chan string, 1)
+	searcher := searcherFunc(func(ctx context.Context, p search.Params) ([]place.Model, error) {
+		switch p.Term {
+		case "slow":
+			<-ctx.Done()
+			cancelled <- p.Term
+			return nil, ctx.Err()
+		case "zzzz":
+			return nil, broker.ErrBadRequest
+		case "panic":
+			panic("search failed")
+		}
+		return []place.Model{{Slug: p.Term, Title: p.Locale}}, nil
+	})
+
+	server := NewServer("", searcher,
+		WithDebounce(50*time.Millisecond),
+		WithAccessLog(AccessLog{Output: ioutil.Discard, SampleRate: 1}),
+	)
+	s := httptest.NewServer(server.Handler)
+	defer s.Close()
+
+	header := http.Header{}
+	header.Set("Accept-Language", "ru")
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/places/typeahead", header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	t.Run("debounce", func(t *testing.T) {
+		for _, term := range []string{"M", "MO", "MOW"} {
+			if err := conn.WriteJSON(search.Params{Term: term}); err != nil {
+				t.Fatalf("write params: %v", err)
+			}
+		}
+
+		expect := typeaheadResult{Term: "MOW", Places: []place.Model{{Slug: "MOW", Title: "ru"}}, Status: http.StatusOK}
+		if got := readResult(t, conn); !reflect.DeepEqual(expect, got) {
+			t.Errorf("expected: %v got: %v", expect, got)
+		}
+	})
+
+	t.Run("cancel outdated", func(t *testing.T) {
+		if err := conn.WriteJSON(search.Params{Term: "slow"}); err != nil {
+			t.Fatalf("write params: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if err := conn.WriteJSON(search.Params{Term: "LED", Locale: "en"}); err != nil {
+			t.Fatalf("write params: %v", err)
+		}
+
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Error("expected outdated search to be cancelled")
+		}
+
+		expect := typeaheadResult{Term: "LED", Places: []place.Model{{Slug: "LED", Title: "en"}}, Status: http.StatusOK}
+		if got := readResult(t, conn); !reflect.DeepEqual(expect, got) {
+			t.Errorf("expected: %v got: %v", expect, got)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		if err := conn.WriteJSON(search.Params{Term: "zzzz"}); err != nil {
+			t.Fatalf("write params: %v", err)
+		}
+
+		expect := typeaheadResult{Term: "zzzz", Status: http.StatusBadRequest, Error: "Bad Request"}
+		if got := readResult(t, conn); !reflect.DeepEqual(expect, got) {
+			t.Errorf("expected: %v got: %v", expect, got)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		if err := conn.WriteJSON(search.Params{Term: "panic"}); err != nil {
+			t.Fatalf("write params: %v", err)
+		}
+
+		expect := typeaheadResult{Term: "panic", Status: http.StatusInternalServerError, Error: "Internal Server Error"}
+		if got := readResult(t, conn); !reflect.DeepEqual(expect, got) {
+			t.Errorf("expected: %v got: %v", expect, got)
+		}
+	})
+}
+
+func TestTypeaheadHandlerOrigin(t *testing.T) {
+	tt := []struct {
+		name       string
+		cors       *CORS
+		origin     string
+		expectCode int
+	}{
+		{
+			name:       "same origin",
+			expectCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:       "cross origin without cors",
+			origin:     "http://example.com",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			name:       "allowed origin",
+			cors:       &CORS{AllowedOrigins: []string{"http://example.com"}},
+			origin:     "http://example.com",
+			expectCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:       "not allowed origin",
+			cors:       &CORS{AllowedOrigins: []string{"http://example.com"}},
+			origin:     "http://example.org",
+			expectCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := httptest.NewServer(newTypeaheadHandler(searcherFunc(nil), time.Millisecond, tc.cors))
+			defer s.Close()
+
+			header := http.Header{}
+			if tc.origin != "" {
+				header.Set("Origin", tc.origin)
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), header)
+			if err == nil {
+				conn.Close()
+			}
+			if resp == nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode != tc.expectCode {
+				t.Errorf("expected: %d got: %d", tc.expectCode, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func readResult(t *testing.T, conn *websocket.Conn) typeaheadResult {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var result typeaheadResult
+	if err := conn.ReadJSON(&result); err != nil {
+		t.Fatalf("read result: %v", err)
+	}
+	return result
+}
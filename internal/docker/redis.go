@@ -1,11 +1,12 @@
 package docker
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 
 	"github.com/ory/dockertest"
 	"github.com/pkg/errors"
@@ -47,7 +48,7 @@ func NewRedis(pool *dockertest.Pool) (*RedisDocker, error) {
 			c := redis.NewClient(&redis.Options{
 				Addr: fmt.Sprintf("localhost:%s", res.GetPort("6379/tcp")),
 			})
-			if _, err := c.Ping().Result(); err != nil {
+			if _, err := c.Ping(context.Background()).Result(); err != nil {
 				return err
 			}
 
@@ -5,4 +5,10 @@ type Model struct {
 	Slug     string `json:"slug"`
 	SubTitle string `json:"subtitle"`
 	Title    string `json:"title"`
+
+	// Type, CountryCode and Weight are used for
+	// filtering and ranking, they are not exposed.
+	Type        string `json:"-"`
+	CountryCode string `json:"-"`
+	Weight      int    `json:"-"`
 }
@@ -0,0 +1,72 @@
+// Package recovery reports panics recovered in handlers
+// and background goroutines.
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+
+	"github.com/romanyx/places/internal/log"
+)
+
+var (
+	panicMeasure = stats.Int64("places/panics", "Number of recovered panics", stats.UnitDimensionless)
+
+	// KeyWhere tags panics with the place they were recovered.
+	KeyWhere = mustKey("where")
+)
+
+// Views contains views of recovered panics.
+var Views = []*view.View{
+	{
+		Name:        "places/panics",
+		Description: "Number of recovered panics by place",
+		Measure:     panicMeasure,
+		TagKeys:     []tag.Key{KeyWhere},
+		Aggregation: view.Count(),
+	},
+}
+
+// Recover recovers panic of the goroutine and reports it, it
+// must be deferred directly:
+//
+//	defer recovery.Recover(ctx, "cache")
+func Recover(ctx context.Context, where string) {
+	if v := recover(); v != nil {
+		Report(ctx, where, v)
+	}
+}
+
+// Report logs recovered value v with stack and trace id,
+// marks span of ctx as failed and counts the panic.
+func Report(ctx context.Context, where string, v interface{}) {
+	span := trace.FromContext(ctx)
+	span.SetStatus(trace.Status{
+		Code:    trace.StatusCodeInternal,
+		Message: fmt.Sprintf("panic: %v", v),
+	})
+
+	ctx, _ = tag.New(ctx, tag.Upsert(KeyWhere, where))
+	stats.Record(ctx, panicMeasure.M(1))
+
+	log.Error(errors.Errorf("panic: %v", v), map[string]interface{}{
+		"where":    where,
+		"trace_id": span.SpanContext().TraceID,
+		"stack":    string(debug.Stack()),
+	})
+}
+
+func mustKey(name string) tag.Key {
+	k, err := tag.NewKey(name)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
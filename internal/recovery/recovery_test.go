@@ -0,0 +1,68 @@
+package recovery
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+
+	"github.com/romanyx/places/internal/log"
+)
+
+type spanExporter struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (e *spanExporter) ExportSpan(s *trace.SpanData) {
+	e.mu.Lock()
+	e.spans = append(e.spans, s)
+	e.mu.Unlock()
+}
+
+func TestRecover(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	if err := view.Register(Views...); err != nil {
+		t.Fatalf("register views: %v", err)
+	}
+	defer view.Unregister(Views...)
+
+	exporter := spanExporter{}
+	trace.RegisterExporter(&exporter)
+	defer trace.UnregisterExporter(&exporter)
+
+	ctx, span := trace.StartSpan(context.Background(), "test", trace.WithSampler(trace.AlwaysSample()))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer Recover(ctx, "test")
+		panic("boom")
+	}()
+	<-done
+	span.End()
+
+	rows, err := view.RetrieveData("places/panics")
+	if err != nil {
+		t.Fatalf("retrieve data: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected one row got: %d", len(rows))
+	}
+	if got := rows[0].Data.(*view.CountData).Value; got != 1 {
+		t.Errorf("expected count: 1 got: %d", got)
+	}
+	if got := rows[0].Tags[0].Value; got != "test" {
+		t.Errorf("expected where: test got: %s", got)
+	}
+
+	if len(exporter.spans) != 1 {
+		t.Fatalf("expected one span got: %d", len(exporter.spans))
+	}
+	if got := exporter.spans[0].Status.Code; got != trace.StatusCodeInternal {
+		t.Errorf("expected status: %d got: %d", trace.StatusCodeInternal, got)
+	}
+}
@@ -0,0 +1,318 @@
+package dataset
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+const (
+	typeCity = "city"
+	// csvColumns is a number of columns in CSV dataset.
+	csvColumns = 6
+)
+
+// Place represents place in dataset. CSV dataset
+// contains the same columns in the same order.
+type Place struct {
+	Locale      string `json:"locale"`
+	Type        string `json:"type"`
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	CityName    string `json:"city_name"`
+	CountryName string `json:"country_name"`
+}
+
+// New loads dataset from CSV or JSON file, format
+// is chosen by the file extension.
+func New(path string) (*Requester, error) {
+	r := Requester{
+		path: path,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Requester searches places in local dataset, it is
+// meant to be the last resort when aviasales is down.
+type Requester struct {
+	path string
+
+	mu      sync.RWMutex
+	index   map[string]*index
+	modTime time.Time
+	size    int64
+}
+
+// Request searches places which name or city name starts with
+// the term or which code equals to the term.
+func (r *Requester) Request(ctx context.Context, p search.Params) ([]place.Model, error) {
+	r.mu.RLock()
+	idx, ok := r.index[p.Locale]
+	r.mu.RUnlock()
+	if !ok {
+		return make([]place.Model, 0), nil
+	}
+
+	return idx.search(p), nil
+}
+
+// Names returns distinct names of places and their
+// cities in a given locale, dataset is shared by tenants.
+func (r *Requester) Names(ctx context.Context, locale string) []string {
+	r.mu.RLock()
+	idx, ok := r.index[locale]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	return idx.titles
+}
+
+// Watch reloads dataset when file changes until ctx is done.
+func (r *Requester) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(r.path)
+		if err != nil {
+			log.Warn(errors.Wrap(err, "stat dataset"), nil)
+			continue
+		}
+
+		r.mu.RLock()
+		changed := !info.ModTime().Equal(r.modTime) || info.Size() != r.size
+		r.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err := r.load(); err != nil {
+			log.Error(errors.Wrap(err, "reload dataset"), nil)
+			continue
+		}
+		log.Info("dataset reloaded", map[string]interface{}{
+			"path": r.path,
+		})
+	}
+}
+
+func (r *Requester) load() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return errors.Wrap(err, "open dataset")
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "stat dataset")
+	}
+
+	var places []Place
+	switch filepath.Ext(r.path) {
+	case ".json":
+		places, err = decodeJSON(f)
+	case ".csv":
+		places, err = decodeCSV(f)
+	default:
+		err = errors.Errorf("unsupported dataset format: %s", r.path)
+	}
+	if err != nil {
+		return err
+	}
+
+	index := buildIndex(places)
+
+	r.mu.Lock()
+	r.index = index
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+	r.mu.Unlock()
+
+	return nil
+}
+
+func decodeJSON(reader io.Reader) ([]Place, error) {
+	var places []Place
+	if err := json.NewDecoder(reader).Decode(&places); err != nil {
+		return nil, errors.Wrap(err, "decode json")
+	}
+
+	return places, nil
+}
+
+func decodeCSV(reader io.Reader) ([]Place, error) {
+	cr := csv.NewReader(reader)
+	cr.FieldsPerRecord = csvColumns
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "decode csv")
+	}
+
+	places := make([]Place, 0, len(records))
+	for _, rec := range records {
+		places = append(places, Place{
+			Locale:      rec[0],
+			Type:        rec[1],
+			Code:        rec[2],
+			Name:        rec[3],
+			CityName:    rec[4],
+			CountryName: rec[5],
+		})
+	}
+
+	return places, nil
+}
+
+// index holds places of one locale.
+type index struct {
+	places []Place
+	// names contains keys sorted by name, which
+	// allows to find name prefix with binary search.
+	names []key
+	codes map[string][]int
+	// titles contains distinct names in original case.
+	titles []string
+}
+
+type key struct {
+	name string
+	i    int
+}
+
+func buildIndex(places []Place) map[string]*index {
+	result := make(map[string]*index)
+	for _, p := range places {
+		idx, ok := result[p.Locale]
+		if !ok {
+			idx = &index{
+				codes: make(map[string][]int),
+			}
+			result[p.Locale] = idx
+		}
+
+		i := len(idx.places)
+		idx.places = append(idx.places, p)
+		idx.codes[strings.ToUpper(p.Code)] = append(idx.codes[strings.ToUpper(p.Code)], i)
+		idx.names = append(idx.names, key{name: strings.ToLower(p.Name), i: i})
+		if p.CityName != "" && p.CityName != p.Name {
+			idx.names = append(idx.names, key{name: strings.ToLower(p.CityName), i: i})
+		}
+	}
+
+	for _, idx := range result {
+		sort.Slice(idx.names, func(i, j int) bool {
+			return idx.names[i].name < idx.names[j].name
+		})
+		idx.titles = titles(idx)
+	}
+
+	return result
+}
+
+func titles(idx *index) []string {
+	result := make([]string, 0, len(idx.names))
+	for i, k := range idx.names {
+		if i > 0 && idx.names[i-1].name == k.name {
+			continue
+		}
+		p := idx.places[k.i]
+		if strings.ToLower(p.Name) == k.name {
+			result = append(result, p.Name)
+			continue
+		}
+		result = append(result, p.CityName)
+	}
+	return result
+}
+
+func (idx *index) search(p search.Params) []place.Model {
+	term := strings.ToLower(strings.TrimSpace(p.Term))
+	result := make([]place.Model, 0)
+	if term == "" {
+		return result
+	}
+
+	seen := make(map[int]struct{})
+	add := func(i int) {
+		if _, ok := seen[i]; ok {
+			return
+		}
+		seen[i] = struct{}{}
+		if !typeAllowed(idx.places[i].Type, p.Types) {
+			return
+		}
+		result = append(result, model(&idx.places[i]))
+	}
+
+	for _, i := range idx.codes[strings.ToUpper(term)] {
+		add(i)
+	}
+
+	start := sort.Search(len(idx.names), func(i int) bool {
+		return idx.names[i].name >= term
+	})
+	for _, k := range idx.names[start:] {
+		if !strings.HasPrefix(k.name, term) {
+			break
+		}
+		add(k.i)
+	}
+
+	return result
+}
+
+func typeAllowed(t string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, allowed := range types {
+		if allowed == t {
+			return true
+		}
+	}
+	return false
+}
+
+func model(p *Place) place.Model {
+	m := place.Model{
+		Slug:  p.Code,
+		Title: p.Name,
+		Type:  p.Type,
+	}
+
+	switch p.Type {
+	case typeCity:
+		m.SubTitle = p.CountryName
+	default:
+		m.SubTitle = p.CityName
+	}
+
+	return m
+}
@@ -0,0 +1,160 @@
+package dataset
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+)
+
+func TestRequesterRequest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dataset")
+	if err != nil {
+		t.Fatalf("create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "places.csv")
+	if err := ioutil.WriteFile(path, []byte(csvDataset), 0600); err != nil {
+		t.Fatalf("write dataset: %v", err)
+	}
+
+	r, err := New(path)
+	if err != nil {
+		t.Fatalf("load dataset: %v", err)
+	}
+
+	tt := []struct {
+		name   string
+		params search.Params
+		expect []place.Model
+	}{
+		{
+			name:   "name prefix",
+			params: search.Params{Term: "mos", Locale: "en"},
+			expect: []place.Model{
+				{Slug: "MOW", Title: "Moscow", SubTitle: "Russia", Type: "city"},
+				{Slug: "SVO", Title: "Sheremetyevo", SubTitle: "Moscow", Type: "airport"},
+			},
+		},
+		{
+			name:   "code",
+			params: search.Params{Term: "svo", Locale: "en"},
+			expect: []place.Model{
+				{Slug: "SVO", Title: "Sheremetyevo", SubTitle: "Moscow", Type: "airport"},
+			},
+		},
+		{
+			name:   "types",
+			params: search.Params{Term: "mos", Locale: "en", Types: []string{"city"}},
+			expect: []place.Model{
+				{Slug: "MOW", Title: "Moscow", SubTitle: "Russia", Type: "city"},
+			},
+		},
+		{
+			name:   "locale",
+			params: search.Params{Term: "мос", Locale: "ru"},
+			expect: []place.Model{
+				{Slug: "MOW", Title: "Москва", SubTitle: "Россия", Type: "city"},
+			},
+		},
+		{
+			name:   "unknown locale",
+			params: search.Params{Term: "mos", Locale: "de"},
+			expect: []place.Model{},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := r.Request(context.Background(), tc.params)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(tc.expect, got) {
+				t.Errorf("expected: %v got: %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestRequesterWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "places.csv")
+	if err := ioutil.WriteFile(path, []byte(csvDataset), 0600); err != nil {
+		t.Fatalf("write dataset: %v", err)
+	}
+
+	r, err := New(path)
+	if err != nil {
+		t.Fatalf("load dataset: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	if err := ioutil.WriteFile(path, []byte("en,city,LED,Saint Petersburg,Saint Petersburg,Russia\n"), 0600); err != nil {
+		t.Fatalf("rewrite dataset: %v", err)
+	}
+	// Modification time is moved forward, since file may be
+	// rewritten within time resolution of fs.
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("touch dataset: %v", err)
+	}
+
+	expect := []place.Model{{Slug: "LED", Title: "Saint Petersburg", SubTitle: "Russia", Type: "city"}}
+	deadline := time.After(time.Second)
+	for {
+		got, err := r.Request(context.Background(), search.Params{Term: "saint", Locale: "en"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if reflect.DeepEqual(expect, got) {
+			break
+		}
+
+		select {
+		case <-deadline:
+			t.Fatalf("expected: %v got: %v", expect, got)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if got, _ := r.Request(context.Background(), search.Params{Term: "mos", Locale: "en"}); len(got) != 0 {
+		t.Errorf("expected places of the old dataset to be gone got: %v", got)
+	}
+}
+
+func TestRequesterNames(t *testing.T) {
+	r := Requester{index: buildIndex([]Place{
+		{Locale: "en", Type: "city", Code: "MOW", Name: "Moscow", CityName: "Moscow"},
+		{Locale: "en", Type: "airport", Code: "SVO", Name: "Sheremetyevo", CityName: "Moscow"},
+		{Locale: "en", Type: "city", Code: "PAR", Name: "Paris", CityName: "Paris"},
+	})}
+
+	expect := []string{"Moscow", "Paris", "Sheremetyevo"}
+	if got := r.Names(context.Background(), "en"); !reflect.DeepEqual(expect, got) {
+		t.Errorf("expected: %v got: %v", expect, got)
+	}
+	if got := r.Names(context.Background(), "ru"); got != nil {
+		t.Errorf("expected no names got: %v", got)
+	}
+}
+
+const csvDataset = `en,city,MOW,Moscow,Moscow,Russia
+en,airport,SVO,Sheremetyevo,Moscow,Russia
+en,city,PAR,Paris,Paris,France
+ru,city,MOW,Москва,Москва,Россия
+`
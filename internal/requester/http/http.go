@@ -3,26 +3,78 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
 
 	"github.com/romanyx/places/internal/broker"
 	"github.com/romanyx/places/internal/place"
 	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/tenant"
 )
 
 const (
 	endpoint = "https://places.aviasales.ru/v2/places.json"
 	typeCity = "city"
+
+	// defaultMaxBody is a default maximum size of response body.
+	defaultMaxBody = 2 << 20
+	// defaultMaxPlaces is a default maximum number of
+	// places decoded from response.
+	defaultMaxPlaces = 100
+)
+
+var (
+	// errBodyTooLarge returns when response body
+	// exceeds maximum size.
+	errBodyTooLarge = errors.New("response body too large")
 )
 
+// Option configures requester.
+type Option func(*Requester)
+
+// WithMaxBody sets maximum size of response body in bytes.
+func WithMaxBody(n int64) Option {
+	return func(r *Requester) {
+		r.maxBody = n
+	}
+}
+
+// WithMaxPlaces sets maximum number of places decoded
+// from response, the rest of response is not read.
+func WithMaxPlaces(n int) Option {
+	return func(r *Requester) {
+		r.maxPlaces = n
+	}
+}
+
+// WithPlainText accepts responses with text/plain content
+// type, for upstreams and proxies which serve JSON as text.
+// Only JSON content types are accepted by default.
+func WithPlainText() Option {
+	return func(r *Requester) {
+		r.plainText = true
+	}
+}
+
 // New initializer for requester.
-func New(client *http.Client) *Requester {
+func New(client *http.Client, opts ...Option) *Requester {
 	r := Requester{
-		client: client,
+		client:    client,
+		maxBody:   defaultMaxBody,
+		maxPlaces: defaultMaxPlaces,
+	}
+	for _, opt := range opts {
+		opt(&r)
 	}
 
 	return &r
@@ -30,12 +82,19 @@ func New(client *http.Client) *Requester {
 
 // Requester http implementation.
 type Requester struct {
-	client *http.Client
+	client    *http.Client
+	maxBody   int64
+	maxPlaces int
+	plainText bool
 }
 
 // Request make request to avaisalves.
 func (r *Requester) Request(ctx context.Context, p search.Params) ([]place.Model, error) {
-	url := endpoint + "?" + queryToString(p)
+	query := queryToString(p)
+	if tq := tenantQuery(ctx); tq != "" {
+		query += "&" + tq
+	}
+	url := endpoint + "?" + query
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "build request")
@@ -44,45 +103,169 @@ func (r *Requester) Request(ctx context.Context, p search.Params) ([]place.Model
 	req = req.WithContext(ctx)
 	resp, err := r.client.Do(req)
 	if err != nil {
-		// Unwrap context errors from client.
-		if deadlineError(err) {
-			return nil, context.DeadlineExceeded
-		}
-		if cancelError(err) {
-			return nil, context.Canceled
-		}
-
-		return nil, errors.Wrap(err, "do request")
+		return nil, requestError(err)
 	}
 	defer resp.Body.Close()
 
 	// Return error if got wrong status code.
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusBadRequest {
-			return nil, broker.ErrBadRequest
+		return nil, statusError(resp)
+	}
+
+	if err := checkContentType(resp.Header.Get("Content-Type"), r.plainText); err != nil {
+		return nil, &broker.Error{Kind: broker.ErrMalformed, Status: resp.StatusCode, Err: err}
+	}
+	if resp.ContentLength > r.maxBody {
+		return nil, &broker.Error{Kind: broker.ErrMalformed, Status: resp.StatusCode, Err: errBodyTooLarge}
+	}
+
+	body := io.LimitedReader{R: resp.Body, N: r.maxBody + 1}
+	result, err := r.decode(ctx, &body)
+	if err != nil {
+		switch {
+		case body.N == 0:
+			// Decoder failed since limit was reached.
+			err = errBodyTooLarge
+		case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+			return nil, requestError(err)
 		}
-		return nil, errors.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &broker.Error{Kind: broker.ErrMalformed, Status: resp.StatusCode, Err: err}
 	}
+	// Rest of truncated body is drained within limit,
+	// so connection may be reused.
+	io.Copy(ioutil.Discard, &body)
 
-	var places []Place
-	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
-		return nil, errors.Wrap(err, "decode body")
+	return result, nil
+}
+
+// decode decodes places from JSON array one by one, entries
+// which do not fit Place or lack code and name are skipped.
+// Decoding stops after maxPlaces.
+func (r *Requester) decode(ctx context.Context, reader io.Reader) ([]place.Model, error) {
+	dec := json.NewDecoder(reader)
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, err
 	}
 
-	result := make([]place.Model, len(places))
-	for i := range places {
-		setPlaceFields(&result[i], &places[i])
+	result := make([]place.Model, 0)
+	var malformed, invalid int64
+	for dec.More() {
+		if len(result) >= r.maxPlaces {
+			stats.Record(ctx, truncatedMeasure.M(1))
+			break
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, errors.Wrap(err, "decode entry")
+		}
+
+		var p Place
+		if err := json.Unmarshal(raw, &p); err != nil {
+			malformed++
+			continue
+		}
+		if p.Code == "" || p.Name == "" {
+			invalid++
+			continue
+		}
+
+		var m place.Model
+		setPlaceFields(&m, &p)
+		result = append(result, m)
 	}
 
+	recordSkipped(ctx, reasonMalformed, malformed)
+	recordSkipped(ctx, reasonInvalid, invalid)
 	return result, nil
 }
 
-func deadlineError(err error) bool {
-	return strings.Contains(err.Error(), "context deadline exceeded")
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return errors.Wrap(err, "decode token")
+	}
+	if token != delim {
+		return errors.Errorf("expected %v got %v", delim, token)
+	}
+	return nil
+}
+
+// checkContentType accepts JSON content types and plain text
+// when it is allowed, so error pages are not decoded.
+func checkContentType(v string, plainText bool) error {
+	if v == "" {
+		return errors.New("missing content type")
+	}
+	mediaType, _, err := mime.ParseMediaType(v)
+	if err != nil {
+		return errors.Wrap(err, "parse content type")
+	}
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return nil
+	case mediaType == "text/plain" && plainText:
+		return nil
+	}
+	return errors.Errorf("unexpected content type: %s", mediaType)
+}
+
+// requestError classifies error of request which got no
+// response. Cancellation is returned as is since it is
+// not a failure of aviasales.
+func requestError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return context.Canceled
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return &broker.Error{Kind: broker.ErrTimeout, Err: context.DeadlineExceeded}
+	}
+
+	return &broker.Error{Kind: broker.ErrUnavailable, Err: err}
+}
+
+// statusError classifies response with unexpected status.
+func statusError(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		return broker.ErrBadRequest
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &broker.Error{
+			Kind:       broker.ErrRateLimited,
+			Status:     resp.StatusCode,
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		return &broker.Error{Kind: broker.ErrTimeout, Status: resp.StatusCode}
+	default:
+		return &broker.Error{
+			Kind:       broker.ErrUnavailable,
+			Status:     resp.StatusCode,
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
 }
 
-func cancelError(err error) bool {
-	return strings.Contains(err.Error(), "context canceled")
+// retryAfter parses Retry-After header, which is
+// either delay in seconds or http date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
 }
 
 func queryToString(p search.Params) string {
@@ -96,18 +279,41 @@ func queryToString(p search.Params) string {
 	return v.Encode()
 }
 
+// tenantQuery returns encoded marker and params of the
+// tenant from ctx, empty for the default tenant.
+func tenantQuery(ctx context.Context) string {
+	t := tenant.FromContext(ctx)
+	if t == nil {
+		return ""
+	}
+
+	v := url.Values{}
+	for key, value := range t.Params {
+		v.Set(key, value)
+	}
+	if t.Marker != "" {
+		v.Set("marker", t.Marker)
+	}
+	return v.Encode()
+}
+
 // Place represents place from aviasales.
 type Place struct {
 	Type        string `json:"type"`
 	Code        string `json:"code"`
 	Name        string `json:"name"`
 	CountryName string `json:"country_name"`
+	CountryCode string `json:"country_code"`
 	CityName    string `json:"city_name"`
+	Weight      int    `json:"weight"`
 }
 
 func setPlaceFields(model *place.Model, place *Place) {
 	model.Slug = place.Code
 	model.Title = place.Name
+	model.Type = place.Type
+	model.CountryCode = place.CountryCode
+	model.Weight = place.Weight
 
 	switch place.Type {
 	case typeCity:
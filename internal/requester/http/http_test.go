@@ -4,14 +4,22 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/broker"
 	"github.com/romanyx/places/internal/place"
 	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/tenant"
 )
 
 func Test_queryToString(t *testing.T) {
@@ -33,30 +41,156 @@ func Test_queryToString(t *testing.T) {
 
 func TestRequesterRequest(t *testing.T) {
 	tt := []struct {
-		name      string
-		expectErr bool
-		handler   func(w http.ResponseWriter, r *http.Request)
-		expect    []place.Model
+		name        string
+		expectErr   error
+		expectRetry time.Duration
+		opts        []Option
+		handler     func(w http.ResponseWriter, r *http.Request)
+		expect      []place.Model
 	}{
 		{
 			name: "ok response",
 			expect: []place.Model{
 				{
-					Slug:     "MOW",
+					Slug:        "MOW",
+					SubTitle:    "Russia",
+					Title:       "Moscow",
+					Type:        "city",
+					CountryCode: "RU",
+					Weight:      1006321,
+				},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				fmt.Fprint(w, okResponse)
+			},
+		},
+		{
+			name: "malformed entries skipped",
+			expect: []place.Model{
+				{
+					Slug:     "LED",
 					SubTitle: "Russia",
-					Title:    "Moscow",
+					Title:    "Saint Petersburg",
+					Type:     "city",
 				},
 			},
 			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `[{"code": 1}, {"name": "Nowhere"}, {"code": "LED", "name": "Saint Petersburg", "type": "city", "country_name": "Russia"}]`)
+			},
+		},
+		{
+			name: "max places",
+			opts: []Option{WithMaxPlaces(1)},
+			expect: []place.Model{
+				{
+					Slug:     "LED",
+					SubTitle: "Russia",
+					Title:    "Saint Petersburg",
+					Type:     "city",
+				},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `[{"code": "LED", "name": "Saint Petersburg", "type": "city", "country_name": "Russia"}, {"code": "MOW", "name": "Moscow"}, {"code":`)
+			},
+		},
+		{
+			name: "body too large",
+			opts: []Option{WithMaxBody(64)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, okResponse)
+			},
+			expectErr: broker.ErrMalformed,
+		},
+		{
+			name: "plain text",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				fmt.Fprint(w, "[]")
+			},
+			expectErr: broker.ErrMalformed,
+		},
+		{
+			name:   "plain text allowed",
+			opts:   []Option{WithPlainText()},
+			expect: []place.Model{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				fmt.Fprint(w, "[]")
+			},
+		},
+		{
+			name: "unexpected content type",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
 				fmt.Fprint(w, okResponse)
 			},
+			expectErr: broker.ErrMalformed,
+		},
+		{
+			name: "not an array",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"error": "oops"}`)
+			},
+			expectErr: broker.ErrMalformed,
+		},
+		{
+			name: "bad request",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+			expectErr: broker.ErrBadRequest,
 		},
 		{
 			name: "bad response",
 			handler: func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusInternalServerError)
 			},
-			expectErr: true,
+			expectErr: broker.ErrUnavailable,
+		},
+		{
+			name: "unavailable with retry after",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectErr:   broker.ErrUnavailable,
+			expectRetry: 2 * time.Minute,
+		},
+		{
+			name: "rate limited",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			expectErr:   broker.ErrRateLimited,
+			expectRetry: 30 * time.Second,
+		},
+		{
+			name: "gateway timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusGatewayTimeout)
+			},
+			expectErr: broker.ErrTimeout,
+		},
+		{
+			name: "malformed response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `[{"code":`)
+			},
+			expectErr: broker.ErrMalformed,
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+			},
+			expectErr: broker.ErrTimeout,
 		},
 	}
 
@@ -67,15 +201,19 @@ func TestRequesterRequest(t *testing.T) {
 
 			client, teardown := newClient(tc.handler)
 			defer teardown()
-			r := New(client)
+			r := New(client, tc.opts...)
 
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 			defer cancel()
 			got, err := r.Request(ctx, search.Params{})
 
-			if tc.expectErr {
-				if err == nil {
-					t.Error("expected error")
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("expected: %v got: %v", tc.expectErr, err)
+				}
+				var upstream *broker.Error
+				if errors.As(err, &upstream) && upstream.RetryAfter != tc.expectRetry {
+					t.Errorf("expected retry after: %v got: %v", tc.expectRetry, upstream.RetryAfter)
 				}
 				return
 			}
@@ -92,6 +230,121 @@ func TestRequesterRequest(t *testing.T) {
 	}
 }
 
+func TestRequesterRequestTenant(t *testing.T) {
+	query := make(chan url.Values, 1)
+	client, teardown := newClient(func(w http.ResponseWriter, r *http.Request) {
+		query <- r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "[]")
+	})
+	defer teardown()
+
+	ctx := tenant.NewContext(context.Background(), &tenant.Tenant{
+		ID:     "acme",
+		Marker: "12345",
+		Params: map[string]string{"currency": "eur"},
+	})
+	if _, err := New(client).Request(ctx, search.Params{Term: "Moscow", Locale: "en"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := <-query
+	expect := url.Values{
+		"term":     {"Moscow"},
+		"locale":   {"en"},
+		"marker":   {"12345"},
+		"currency": {"eur"},
+	}
+	if !reflect.DeepEqual(expect, got) {
+		t.Errorf("expected: %v got: %v", expect, got)
+	}
+}
+
+func TestRequesterRequestDrain(t *testing.T) {
+	// Body is larger than buffer of decoder.
+	body := strings.NewReader("[" + strings.Repeat(`{"code": "MOW", "name": "Moscow"}, `, 1000) + `{"code": "LED", "name": "Saint Petersburg"}]`)
+	client := &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        http.Header{"Content-Type": {"application/json"}},
+				Body:          ioutil.NopCloser(body),
+				ContentLength: -1,
+			}, nil
+		}),
+	}
+
+	got, err := New(client, WithMaxPlaces(1)).Request(context.Background(), search.Params{Term: "Moscow", Locale: "en"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("expected 1 place got: %d", len(got))
+	}
+	if body.Len() != 0 {
+		t.Errorf("expected drained body got %d bytes left", body.Len())
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestCheckContentType(t *testing.T) {
+	tt := []struct {
+		name      string
+		value     string
+		plainText bool
+		expectErr bool
+	}{
+		{name: "empty", expectErr: true},
+		{name: "json", value: "application/json"},
+		{name: "json with charset", value: "application/json; charset=utf-8"},
+		{name: "json suffix", value: "application/vnd.places+json"},
+		{name: "plain text", value: "text/plain; charset=utf-8", expectErr: true},
+		{name: "plain text allowed", value: "text/plain; charset=utf-8", plainText: true},
+		{name: "html", value: "text/html", expectErr: true},
+		{name: "html with plain text allowed", value: "text/html", plainText: true, expectErr: true},
+		{name: "invalid", value: "/", expectErr: true},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := checkContentType(tc.value, tc.plainText); (err != nil) != tc.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tt := []struct {
+		name   string
+		value  string
+		expect time.Duration
+	}{
+		{name: "empty"},
+		{name: "seconds", value: "5", expect: 5 * time.Second},
+		{name: "negative", value: "-5"},
+		{name: "past date", value: "Wed, 21 Oct 2015 07:28:00 GMT"},
+		{name: "invalid", value: "soon"},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := retryAfter(tc.value); got != tc.expect {
+				t.Errorf("expected: %v got: %v", tc.expect, got)
+			}
+		})
+	}
+}
+
 func newClient(handler http.HandlerFunc) (*http.Client, func()) {
 	s := httptest.NewTLSServer(handler)
 
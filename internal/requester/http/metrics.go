@@ -0,0 +1,61 @@
+package http
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/romanyx/places/internal/tenant"
+)
+
+// Decode measures.
+var (
+	skippedMeasure   = stats.Int64("places/requester/skipped", "Number of skipped response entries", stats.UnitDimensionless)
+	truncatedMeasure = stats.Int64("places/requester/truncated", "Number of responses truncated to maximum number of places", stats.UnitDimensionless)
+
+	keyReason = mustKey("reason")
+)
+
+// Skip reasons.
+const (
+	// reasonMalformed marks entries which do not fit Place.
+	reasonMalformed = "malformed"
+	// reasonInvalid marks entries without code or name.
+	reasonInvalid = "invalid"
+)
+
+// Views contains views of response decoding.
+var Views = []*view.View{
+	{
+		Name:        "places/requester/skipped",
+		Description: "Number of skipped response entries by reason",
+		Measure:     skippedMeasure,
+		TagKeys:     []tag.Key{keyReason, tenant.Key},
+		Aggregation: view.Sum(),
+	},
+	{
+		Name:        "places/requester/truncated",
+		Description: "Number of responses truncated to maximum number of places",
+		Measure:     truncatedMeasure,
+		TagKeys:     []tag.Key{tenant.Key},
+		Aggregation: view.Count(),
+	},
+}
+
+func recordSkipped(ctx context.Context, reason string, n int64) {
+	if n == 0 {
+		return
+	}
+	ctx, _ = tag.New(ctx, tag.Upsert(keyReason, reason))
+	stats.Record(ctx, skippedMeasure.M(n))
+}
+
+func mustKey(name string) tag.Key {
+	k, err := tag.NewKey(name)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
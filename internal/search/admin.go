@@ -0,0 +1,71 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/storage"
+)
+
+// Purge deletes cached entries which match term and locale,
+// empty term or locale matches any. Returns number of
+// deleted entries. Deleting an entry clears its negative
+// entry and slug index too, negative entries of params
+// without cached places are not listed and expire by TTL.
+func (s *Service) Purge(ctx context.Context, term, locale string) (int, error) {
+	entries, err := s.List(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "list entries")
+	}
+
+	var deleted int
+	for _, e := range entries {
+		if term != "" && !strings.EqualFold(e.Params.Term, term) {
+			continue
+		}
+		if locale != "" && e.Params.Locale != locale {
+			continue
+		}
+
+		if err := s.Delete(ctx, e.Params); err != nil {
+			// Entry expired after it was listed.
+			if errors.Cause(err) == storage.ErrCacheNotFound {
+				continue
+			}
+			return deleted, errors.Wrap(err, "delete entry")
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// Refresh requests places and replaces their cache. Previous
+// entry is deleted with its negative entry, since search
+// answers from negative entry before requesting places.
+func (s *Service) Refresh(ctx context.Context, p Params) ([]place.Model, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	places, err := s.Request(ctx, p)
+	if err != nil {
+		return nil, errors.Wrap(err, "request")
+	}
+	places = s.process(p, places)
+
+	if err := s.Delete(ctx, p); err != nil && errors.Cause(err) != storage.ErrCacheNotFound {
+		return nil, errors.Wrap(err, "delete")
+	}
+
+	if err := s.Cache(ctx, p, places); err != nil {
+		return nil, errors.Wrap(err, "cache")
+	}
+	if err := s.IndexPlaces(ctx, p.Locale, places); err != nil {
+		return nil, errors.Wrap(err, "index")
+	}
+
+	return places, nil
+}
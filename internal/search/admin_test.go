@@ -0,0 +1,98 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/storage"
+)
+
+func TestServicePurge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := NewMockRepository(ctrl)
+	repo.EXPECT().
+		List(gomock.Any()).
+		Return([]Entry{
+			{Params: Params{Term: "Moscow", Locale: "en"}},
+			{Params: Params{Term: "moscow", Locale: "ru"}},
+			{Params: Params{Term: "Paris", Locale: "en"}},
+		}, nil)
+	repo.EXPECT().
+		Delete(gomock.Any(), Params{Term: "Moscow", Locale: "en"}).
+		Return(nil)
+
+	s := NewService(nil, repo, time.Second)
+	deleted, err := s.Purge(context.Background(), "MOSCOW", "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted entry got: %d", deleted)
+	}
+}
+
+func TestServiceRefresh(t *testing.T) {
+	moscow := Params{Term: "Moscow", Locale: "en"}
+	places := []place.Model{{Slug: "MOW"}}
+
+	tt := []struct {
+		name      string
+		deleteErr error
+		expectErr bool
+	}{
+		{
+			name: "negative entry",
+		},
+		{
+			name:      "no entry",
+			deleteErr: storage.ErrCacheNotFound,
+		},
+		{
+			name:      "delete failed",
+			deleteErr: errors.New("unexpected error"),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Negative entry is deleted before places are
+			// cached, so search does not answer from it.
+			repo := NewMockRepository(ctrl)
+			deleted := repo.EXPECT().
+				Delete(gomock.Any(), moscow).
+				Return(tc.deleteErr)
+			if !tc.expectErr {
+				cache := repo.EXPECT().
+					Cache(gomock.Any(), moscow, places).
+					Return(nil).
+					After(deleted)
+				repo.EXPECT().
+					IndexPlaces(gomock.Any(), "en", places).
+					Return(nil).
+					After(cache)
+			}
+
+			s := NewService(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+				return []place.Model{{Slug: "MOW"}}, nil
+			}), repo, time.Second)
+			_, err := s.Refresh(context.Background(), moscow)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error: %t got: %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+
+	"github.com/romanyx/places/internal/broker"
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/place"
+)
+
+var (
+	// ErrCooldown is an underlying error of rate limited
+	// error returned without request during cooldown.
+	ErrCooldown = errors.New("cooldown")
+)
+
+// Cooldown measures.
+var (
+	cooldownMeasure = stats.Int64("places/cooldown/started", "Number of started cooldowns", stats.UnitDimensionless)
+	delayMeasure    = stats.Int64("places/cooldown/delay", "Duration of started cooldowns", stats.UnitMilliseconds)
+	skipMeasure     = stats.Int64("places/cooldown/skipped", "Number of requests skipped during cooldown", stats.UnitDimensionless)
+
+	keySource = mustKey("source")
+)
+
+// Cooldown sources.
+const (
+	// sourceUpstream marks cooldown started on
+	// rate limited response.
+	sourceUpstream = "upstream"
+	// sourceShared marks cooldown started by
+	// another replica.
+	sourceShared = "shared"
+)
+
+// CooldownViews contains views of the cooldown.
+var CooldownViews = []*view.View{
+	{
+		Name:        "places/cooldown/started",
+		Description: "Number of started cooldowns by source",
+		Measure:     cooldownMeasure,
+		TagKeys:     []tag.Key{keySource},
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "places/cooldown/delay",
+		Description: "Distribution of cooldown durations",
+		Measure:     delayMeasure,
+		Aggregation: view.Distribution(1000, 5000, 15000, 30000, 60000, 120000, 300000, 600000),
+	},
+	{
+		Name:        "places/cooldown/skipped",
+		Description: "Number of requests skipped during cooldown",
+		Measure:     skipMeasure,
+		Aggregation: view.Count(),
+	},
+}
+
+// Cooldown stores end of the cooldown shared by replicas.
+type Cooldown interface {
+	// Until returns end of the cooldown, zero
+	// time when there is no cooldown.
+	Until(context.Context) (time.Time, error)
+	// Start starts cooldown for a given duration,
+	// longer cooldown which already started is kept.
+	Start(context.Context, time.Duration) error
+}
+
+// CooldownConfig configures cooldown.
+type CooldownConfig struct {
+	// Default is a duration of cooldown when rate
+	// limited response has no Retry-After.
+	Default time.Duration
+	// Max bounds duration of cooldown.
+	Max time.Duration
+	// Refresh is an interval of shared
+	// cooldown checks.
+	Refresh time.Duration
+}
+
+// RequesterWithCooldown decorates requester with cooldown.
+// When aviasales rate limits request, requests are skipped
+// until Retry-After passes. Cooldown is shared through store,
+// so replicas stop requesting too. Store is checked at most
+// once per refresh interval and is optional.
+type RequesterWithCooldown struct {
+	base  Requester
+	store Cooldown
+	cfg   CooldownConfig
+	now   func() time.Time
+
+	mu      sync.Mutex
+	until   time.Time
+	checked time.Time
+}
+
+// NewRequesterWithCooldown initialize decorator.
+func NewRequesterWithCooldown(requester Requester, store Cooldown, cfg CooldownConfig) Requester {
+	s := RequesterWithCooldown{
+		base:  requester,
+		store: store,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+
+	return &s
+}
+
+// Request decoraters request method. Returns rate limited
+// error without calling base requester during cooldown.
+func (s *RequesterWithCooldown) Request(ctx context.Context, p Params) ([]place.Model, error) {
+	if remaining := s.remaining(ctx); remaining > 0 {
+		stats.Record(ctx, skipMeasure.M(1))
+		trace.FromContext(ctx).Annotate([]trace.Attribute{
+			trace.Int64Attribute("remaining_ms", int64(remaining/time.Millisecond)),
+		}, "request skipped during cooldown")
+		return nil, &broker.Error{Kind: broker.ErrRateLimited, RetryAfter: remaining, Err: ErrCooldown}
+	}
+
+	places, err := s.base.Request(ctx, p)
+	if errors.Is(err, broker.ErrRateLimited) {
+		s.start(ctx, err)
+	}
+	return places, err
+}
+
+// remaining returns remaining time of the cooldown.
+func (s *RequesterWithCooldown) remaining(ctx context.Context) time.Duration {
+	now := s.now()
+
+	s.mu.Lock()
+	if now.Before(s.until) {
+		defer s.mu.Unlock()
+		return s.until.Sub(now)
+	}
+	check := s.store != nil && now.Sub(s.checked) >= s.cfg.Refresh
+	if check {
+		s.checked = now
+	}
+	s.mu.Unlock()
+
+	if !check {
+		return 0
+	}
+
+	until, err := s.store.Until(ctx)
+	if err != nil {
+		// Request anyway when store fails.
+		log.Warn(errors.Wrap(err, "check cooldown"), nil)
+		return 0
+	}
+	if !now.Before(until) {
+		return 0
+	}
+
+	s.mu.Lock()
+	if until.After(s.until) {
+		s.until = until
+	}
+	s.mu.Unlock()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(keySource, sourceShared))
+	stats.Record(ctx, cooldownMeasure.M(1))
+	return until.Sub(now)
+}
+
+func (s *RequesterWithCooldown) start(ctx context.Context, err error) {
+	delay := s.cfg.Default
+	var upstream *broker.Error
+	if errors.As(err, &upstream) && upstream.RetryAfter > 0 {
+		delay = upstream.RetryAfter
+	}
+	if s.cfg.Max > 0 && delay > s.cfg.Max {
+		delay = s.cfg.Max
+	}
+	if delay <= 0 {
+		return
+	}
+
+	until := s.now().Add(delay)
+	s.mu.Lock()
+	if until.After(s.until) {
+		s.until = until
+	}
+	s.mu.Unlock()
+
+	ctx, _ = tag.New(ctx, tag.Upsert(keySource, sourceUpstream))
+	stats.Record(ctx, cooldownMeasure.M(1), delayMeasure.M(int64(delay/time.Millisecond)))
+	trace.FromContext(ctx).Annotate([]trace.Attribute{
+		trace.Int64Attribute("delay_ms", int64(delay/time.Millisecond)),
+	}, "cooldown started")
+
+	if s.store == nil {
+		return
+	}
+	if err := s.store.Start(ctx, delay); err != nil {
+		log.Warn(errors.Wrap(err, "start shared cooldown"), nil)
+	}
+}
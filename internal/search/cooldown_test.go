@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/romanyx/places/internal/broker"
+	"github.com/romanyx/places/internal/place"
+)
+
+func TestRequesterWithCooldown(t *testing.T) {
+	var requests int
+	var err error
+	store := &cooldownStore{}
+	r := NewRequesterWithCooldown(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+		requests++
+		return nil, err
+	}), store, CooldownConfig{Default: time.Second, Max: time.Minute}).(*RequesterWithCooldown)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	err = &broker.Error{Kind: broker.ErrRateLimited, RetryAfter: time.Hour}
+	r.Request(context.Background(), Params{})
+	if store.started != time.Minute {
+		t.Errorf("expected shared cooldown bounded by max got: %v", store.started)
+	}
+
+	err = nil
+	_, got := r.Request(context.Background(), Params{})
+	if !errors.Is(got, broker.ErrRateLimited) || !errors.Is(got, ErrCooldown) {
+		t.Errorf("expected cooldown error got: %v", got)
+	}
+	if requests != 1 {
+		t.Errorf("expected request to be skipped during cooldown")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, got := r.Request(context.Background(), Params{}); got != nil {
+		t.Errorf("unexpected error after cooldown: %v", got)
+	}
+}
+
+func TestRequesterWithCooldownShared(t *testing.T) {
+	var requests int
+	store := &cooldownStore{until: time.Now().Add(time.Minute)}
+	r := NewRequesterWithCooldown(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+		requests++
+		return nil, nil
+	}), store, CooldownConfig{})
+
+	_, err := r.Request(context.Background(), Params{})
+	var upstream *broker.Error
+	if !errors.As(err, &upstream) || upstream.RetryAfter <= 0 {
+		t.Errorf("expected rate limited error with retry after got: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected request to be skipped during shared cooldown")
+	}
+}
+
+type cooldownStore struct {
+	until   time.Time
+	started time.Duration
+}
+
+func (s *cooldownStore) Until(context.Context) (time.Time, error) {
+	return s.until, nil
+}
+
+func (s *cooldownStore) Start(ctx context.Context, d time.Duration) error {
+	s.started = d
+	return nil
+}
@@ -0,0 +1,192 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/romanyx/places/internal/broker"
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/tenant"
+)
+
+var (
+	// ErrSaturated returns when requester has no capacity
+	// for one more request.
+	ErrSaturated = errors.New("requester saturated")
+)
+
+// Limiter measures.
+var (
+	limitMeasure    = stats.Int64("places/limiter/limit", "Current concurrency limit of requests", stats.UnitDimensionless)
+	inflightMeasure = stats.Int64("places/limiter/inflight", "Number of requests in flight", stats.UnitDimensionless)
+	rejectMeasure   = stats.Int64("places/limiter/rejected", "Number of rejected requests", stats.UnitDimensionless)
+
+	keyReason = mustKey("reason")
+)
+
+// Rejection reasons.
+const (
+	reasonLimit = "limit"
+	reasonTerm  = "term"
+)
+
+// LimiterViews contains views of the limiter state.
+var LimiterViews = []*view.View{
+	{
+		Name:        "places/limiter/limit",
+		Description: "Current concurrency limit of requests",
+		Measure:     limitMeasure,
+		TagKeys:     []tag.Key{tenant.Key},
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "places/limiter/inflight",
+		Description: "Number of requests in flight",
+		Measure:     inflightMeasure,
+		TagKeys:     []tag.Key{tenant.Key},
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "places/limiter/rejected",
+		Description: "Number of rejected requests by reason",
+		Measure:     rejectMeasure,
+		TagKeys:     []tag.Key{keyReason, tenant.Key},
+		Aggregation: view.Count(),
+	},
+}
+
+// LimitConfig configures adaptive concurrency limit.
+type LimitConfig struct {
+	// Initial is a limit on start.
+	Initial int
+	// Min and Max bound the limit.
+	Min, Max int
+	// Backoff is a multiplier applied to the limit
+	// when request times out.
+	Backoff float64
+	// PerTerm limits concurrent requests with the same
+	// params, zero disables it.
+	PerTerm int
+}
+
+// Validate checks that limit is able to recover, limit
+// of zero admits no requests, so none of them succeeds
+// to grow it back, and that it shrinks on timeouts.
+func (cfg LimitConfig) Validate() error {
+	if cfg.Min < 1 {
+		return errors.Errorf("minimal limit %d is less than 1", cfg.Min)
+	}
+	if cfg.Max < cfg.Min {
+		return errors.Errorf("maximal limit %d is less than minimal %d", cfg.Max, cfg.Min)
+	}
+	if cfg.Initial < cfg.Min || cfg.Initial > cfg.Max {
+		return errors.Errorf("initial limit %d is out of [%d, %d]", cfg.Initial, cfg.Min, cfg.Max)
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		return errors.Errorf("backoff %g is out of (0, 1)", cfg.Backoff)
+	}
+	return nil
+}
+
+// RequesterWithLimit decorates requester with adaptive
+// concurrency limit. Limit grows additively on every
+// successful request and shrinks multiplicatively when
+// request deadline exceeds.
+type RequesterWithLimit struct {
+	base Requester
+	cfg  LimitConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	terms    map[string]int
+}
+
+// NewRequesterWithLimit initialize decorator, returns
+// error when config is invalid.
+func NewRequesterWithLimit(requester Requester, cfg LimitConfig) (Requester, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "validate limit")
+	}
+
+	s := RequesterWithLimit{
+		base:  requester,
+		cfg:   cfg,
+		limit: float64(cfg.Initial),
+		terms: make(map[string]int),
+	}
+	s.clamp()
+
+	return &s, nil
+}
+
+// Request decoraters request method. Returns ErrSaturated
+// without calling base requester when limit is reached.
+func (s *RequesterWithLimit) Request(ctx context.Context, p Params) ([]place.Model, error) {
+	key := fmt.Sprint(p)
+	if reason, ok := s.acquire(ctx, key); !ok {
+		ctx, _ = tag.New(ctx, tag.Upsert(keyReason, reason))
+		stats.Record(ctx, rejectMeasure.M(1))
+		return nil, ErrSaturated
+	}
+
+	places, err := s.base.Request(ctx, p)
+	dropped := errors.Is(err, context.DeadlineExceeded) || errors.Is(err, broker.ErrTimeout)
+	s.release(ctx, key, dropped, err == nil)
+	return places, err
+}
+
+func (s *RequesterWithLimit) acquire(ctx context.Context, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inflight >= int(s.limit) {
+		return reasonLimit, false
+	}
+	if s.cfg.PerTerm > 0 && s.terms[key] >= s.cfg.PerTerm {
+		return reasonTerm, false
+	}
+
+	s.inflight++
+	s.terms[key]++
+	stats.Record(ctx, inflightMeasure.M(int64(s.inflight)))
+	return "", true
+}
+
+func (s *RequesterWithLimit) release(ctx context.Context, key string, dropped, succeeded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inflight--
+	if s.terms[key]--; s.terms[key] <= 0 {
+		delete(s.terms, key)
+	}
+
+	switch {
+	case dropped:
+		s.limit *= s.cfg.Backoff
+	case succeeded:
+		s.limit += 1 / s.limit
+	}
+	s.clamp()
+
+	stats.Record(ctx,
+		inflightMeasure.M(int64(s.inflight)),
+		limitMeasure.M(int64(s.limit)),
+	)
+}
+
+func (s *RequesterWithLimit) clamp() {
+	if s.limit < float64(s.cfg.Min) {
+		s.limit = float64(s.cfg.Min)
+	}
+	if s.limit > float64(s.cfg.Max) {
+		s.limit = float64(s.cfg.Max)
+	}
+}
@@ -0,0 +1,144 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/romanyx/places/internal/place"
+)
+
+func TestRequesterWithLimitRequest(t *testing.T) {
+	tt := []struct {
+		name      string
+		cfg       LimitConfig
+		params    Params
+		expectErr error
+	}{
+		{
+			name:   "capacity available",
+			cfg:    LimitConfig{Initial: 2, Min: 1, Max: 2, Backoff: 0.5},
+			params: Params{Term: "Paris"},
+		},
+		{
+			name:      "limit reached",
+			cfg:       LimitConfig{Initial: 1, Min: 1, Max: 1, Backoff: 0.5},
+			params:    Params{Term: "Paris"},
+			expectErr: ErrSaturated,
+		},
+		{
+			name:      "term limit reached",
+			cfg:       LimitConfig{Initial: 2, Min: 1, Max: 2, Backoff: 0.5, PerTerm: 1},
+			params:    Params{Term: "Moscow"},
+			expectErr: ErrSaturated,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			r, err := NewRequesterWithLimit(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+				if p.Term == "Moscow" {
+					close(started)
+					<-release
+				}
+				return nil, nil
+			}), tc.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Occupy one slot with a blocked request.
+			go r.Request(context.Background(), Params{Term: "Moscow"})
+			<-started
+			defer close(release)
+
+			_, err = r.Request(context.Background(), tc.params)
+			if err != tc.expectErr {
+				t.Errorf("expected: %v got: %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRequesterWithLimitAdapt(t *testing.T) {
+	var err error
+	requester, _ := NewRequesterWithLimit(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+		return nil, err
+	}), LimitConfig{Initial: 10, Min: 1, Max: 20, Backoff: 0.5})
+	r := requester.(*RequesterWithLimit)
+
+	r.Request(context.Background(), Params{})
+	if r.limit <= 10 {
+		t.Errorf("expected limit to grow on success, got: %v", r.limit)
+	}
+
+	err = context.DeadlineExceeded
+	r.Request(context.Background(), Params{})
+	if r.limit >= 10 {
+		t.Errorf("expected limit to shrink on timeout, got: %v", r.limit)
+	}
+}
+
+func TestNewRequesterWithLimitValidate(t *testing.T) {
+	tt := []struct {
+		name      string
+		cfg       LimitConfig
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			cfg:  LimitConfig{Initial: 1, Min: 1, Max: 1, Backoff: 0.5},
+		},
+		{
+			name:      "zero minimum",
+			cfg:       LimitConfig{Initial: 1, Min: 0, Max: 10, Backoff: 0.5},
+			expectErr: true,
+		},
+		{
+			name:      "maximum less than minimum",
+			cfg:       LimitConfig{Initial: 1, Min: 5, Max: 2, Backoff: 0.5},
+			expectErr: true,
+		},
+		{
+			name:      "initial less than minimum",
+			cfg:       LimitConfig{Initial: 1, Min: 2, Max: 10, Backoff: 0.5},
+			expectErr: true,
+		},
+		{
+			name:      "initial greater than maximum",
+			cfg:       LimitConfig{Initial: 20, Min: 1, Max: 10, Backoff: 0.5},
+			expectErr: true,
+		},
+		{
+			name:      "zero backoff",
+			cfg:       LimitConfig{Initial: 1, Min: 1, Max: 10},
+			expectErr: true,
+		},
+		{
+			name:      "negative backoff",
+			cfg:       LimitConfig{Initial: 1, Min: 1, Max: 10, Backoff: -0.5},
+			expectErr: true,
+		},
+		{
+			name:      "backoff of one",
+			cfg:       LimitConfig{Initial: 1, Min: 1, Max: 10, Backoff: 1},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewRequesterWithLimit(requesterFunc(nil), tc.cfg)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("expected error: %t got: %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Locales maps locale to the locale which should
+// be used when there are no results for it.
+type Locales map[string]string
+
+// ParseLocales parses comma separated list of
+// fallbacks in form "uk:ru,ru:en".
+func ParseLocales(s string) (Locales, error) {
+	locales := make(Locales)
+	if s == "" {
+		return locales, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid locale fallback: %q", pair)
+		}
+		locales[parts[0]] = parts[1]
+	}
+
+	return locales, nil
+}
+
+// Chain returns locale followed by its fallbacks.
+func (l Locales) Chain(locale string) []string {
+	chain := []string{locale}
+	seen := map[string]struct{}{locale: {}}
+	for {
+		next, ok := l[locale]
+		if !ok {
+			return chain
+		}
+		if _, ok := seen[next]; ok {
+			return chain
+		}
+
+		seen[next] = struct{}{}
+		chain = append(chain, next)
+		locale = next
+	}
+}
@@ -0,0 +1,34 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocalesChain(t *testing.T) {
+	locales, err := ParseLocales("uk:ru,ru:en,en:uk")
+	if err != nil {
+		t.Fatalf("parse locales: %v", err)
+	}
+
+	tt := []struct {
+		locale string
+		expect []string
+	}{
+		{locale: "uk", expect: []string{"uk", "ru", "en"}},
+		{locale: "ru", expect: []string{"ru", "en", "uk"}},
+		{locale: "de", expect: []string{"de"}},
+	}
+
+	for _, tc := range tt {
+		if got := locales.Chain(tc.locale); !reflect.DeepEqual(tc.expect, got) {
+			t.Errorf("expected: %v got: %v", tc.expect, got)
+		}
+	}
+}
+
+func TestParseLocalesInvalid(t *testing.T) {
+	if _, err := ParseLocales("uk-ru"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/storage"
+)
+
+var (
+	// ErrNotFound returns when there is no place with
+	// a given slug.
+	ErrNotFound = errors.New("place not found")
+)
+
+// Lookup finds place by its slug in a given locale. It will
+// try to find place in the slug index, which fills as searches
+// return results, if place is not indexed yet it will request
+// aviasales using slug as a term and pick place with the same
+// slug. Returns ErrNotFound if aviasales has no such place and
+// UnavailableError if request failed.
+func (s *Service) Lookup(ctx context.Context, slug, locale string) (place.Model, error) {
+	spanCtx := trace.FromContext(ctx).SpanContext()
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	slug = strings.ToUpper(slug)
+	model, err := s.RetrievePlace(ctx, slug, locale)
+	if err == nil {
+		setCached(ctx)
+		return model, nil
+	}
+	if errors.Cause(err) != storage.ErrCacheNotFound {
+		log.Error(errors.Wrap(err, "unexpected error on retrieve place"), map[string]interface{}{
+			"trace_id": spanCtx.TraceID,
+		})
+	}
+
+	places, err := s.Request(ctx, Params{Term: slug, Locale: locale})
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return place.Model{}, err
+		}
+		return place.Model{}, &UnavailableError{Err: err}
+	}
+
+	// Indexed place is processed as searched ones are.
+	for _, m := range s.pipeline.Process(Params{Term: slug, Locale: locale}, places) {
+		if m.Slug != slug {
+			continue
+		}
+
+		if err := s.IndexPlaces(ctx, locale, []place.Model{m}); err != nil {
+			log.Error(errors.Wrap(err, "index failed"), map[string]interface{}{
+				"trace_id": spanCtx.TraceID,
+			})
+		}
+		return m, nil
+	}
+
+	return place.Model{}, ErrNotFound
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/storage"
+)
+
+func TestServiceLookup(t *testing.T) {
+	moscow := place.Model{Slug: "MOW", Title: "Moscow", SubTitle: "Russia"}
+
+	tt := []struct {
+		name          string
+		requesterFunc func(ctx context.Context, p Params) ([]place.Model, error)
+		repoFunc      func(m *MockRepository)
+		opts          []Option
+		expect        place.Model
+		expectErr     error
+	}{
+		{
+			name: "indexed",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return nil, context.DeadlineExceeded
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrievePlace(gomock.Any(), "MOW", "en").
+					Return(moscow, nil)
+			},
+			expect: moscow,
+		},
+		{
+			name: "upstream fallback",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return []place.Model{{Slug: "VKO", Title: "Vnukovo"}, moscow}, nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrievePlace(gomock.Any(), "MOW", "en").
+					Return(place.Model{}, storage.ErrCacheNotFound)
+				m.EXPECT().
+					IndexPlaces(gomock.Any(), "en", []place.Model{moscow}).
+					Return(nil)
+			},
+			expect: moscow,
+		},
+		{
+			name: "pipeline before index",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return []place.Model{{Slug: "MOW", Title: " Moscow ", SubTitle: "Russia"}}, nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrievePlace(gomock.Any(), "MOW", "en").
+					Return(place.Model{}, storage.ErrCacheNotFound)
+				m.EXPECT().
+					IndexPlaces(gomock.Any(), "en", []place.Model{moscow}).
+					Return(nil)
+			},
+			opts:   []Option{WithPipeline(Pipeline{NormalizeStep()})},
+			expect: moscow,
+		},
+		{
+			name: "not found",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return []place.Model{{Slug: "VKO", Title: "Vnukovo"}}, nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrievePlace(gomock.Any(), "MOW", "en").
+					Return(place.Model{}, storage.ErrCacheNotFound)
+			},
+			expectErr: ErrNotFound,
+		},
+		{
+			name: "unavailable",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return nil, context.DeadlineExceeded
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrievePlace(gomock.Any(), "MOW", "en").
+					Return(place.Model{}, storage.ErrCacheNotFound)
+			},
+			expectErr: ErrUnavailable,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo := NewMockRepository(ctrl)
+			tc.repoFunc(repo)
+
+			s := NewService(requesterFunc(tc.requesterFunc), repo, time.Second, tc.opts...)
+			got, err := s.Lookup(context.Background(), "mow", "en")
+
+			if !errors.Is(err, tc.expectErr) {
+				t.Errorf("expected error: %v got: %v", tc.expectErr, err)
+				return
+			}
+
+			if !reflect.DeepEqual(tc.expect, got) {
+				t.Errorf("expected: %v got: %v", tc.expect, got)
+			}
+		})
+	}
+}
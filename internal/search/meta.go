@@ -0,0 +1,65 @@
+package search
+
+import (
+	"context"
+	"time"
+)
+
+type metaKey struct{}
+
+// Meta describes how search result was produced.
+type Meta struct {
+	// Cached is true when result was retrieved from cache.
+	Cached bool
+	// Stale is true when result was retrieved from cache
+	// because request failed.
+	Stale bool
+	// CachedAt is a time when stale result was cached.
+	CachedAt time.Time
+	// Degraded is true when result was found in
+	// fallback dataset.
+	Degraded bool
+	// Corrected is a term which gave places when
+	// requested term gave none.
+	Corrected string
+}
+
+// WithMeta returns copy of ctx which carries meta, service
+// fills it during search.
+func WithMeta(ctx context.Context) (context.Context, *Meta) {
+	m := new(Meta)
+	return context.WithValue(ctx, metaKey{}, m), m
+}
+
+// MetaFromContext returns meta stored in ctx, or nil when
+// there is no meta.
+func MetaFromContext(ctx context.Context) *Meta {
+	m, _ := ctx.Value(metaKey{}).(*Meta)
+	return m
+}
+
+func setCached(ctx context.Context) {
+	if m := MetaFromContext(ctx); m != nil {
+		m.Cached = true
+	}
+}
+
+func setStale(ctx context.Context, cachedAt time.Time) {
+	if m := MetaFromContext(ctx); m != nil {
+		m.Cached = true
+		m.Stale = true
+		m.CachedAt = cachedAt
+	}
+}
+
+func setDegraded(ctx context.Context) {
+	if m := MetaFromContext(ctx); m != nil {
+		m.Degraded = true
+	}
+}
+
+func setCorrected(ctx context.Context, term string) {
+	if m := MetaFromContext(ctx); m != nil {
+		m.Corrected = term
+	}
+}
@@ -0,0 +1,11 @@
+package search
+
+import "go.opencensus.io/tag"
+
+func mustKey(name string) tag.Key {
+	k, err := tag.NewKey(name)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/romanyx/places/internal/place"
+)
+
+// Step processes requested places, it may modify
+// places in place and returns resulting slice.
+type Step func(Params, []place.Model) []place.Model
+
+// Pipeline applies steps to requested places in order,
+// before they are ranked and cached.
+type Pipeline []Step
+
+// Process applies steps of the pipeline.
+func (pl Pipeline) Process(p Params, places []place.Model) []place.Model {
+	for _, step := range pl {
+		places = step(p, places)
+	}
+	return places
+}
+
+// DedupeStep drops places with the same slug and type
+// as one of the previous places.
+func DedupeStep() Step {
+	return func(p Params, places []place.Model) []place.Model {
+		type key struct{ slug, typ string }
+		seen := make(map[key]struct{}, len(places))
+		result := places[:0]
+		for _, m := range places {
+			k := key{slug: strings.ToUpper(m.Slug), typ: m.Type}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			result = append(result, m)
+		}
+		return result
+	}
+}
+
+// NormalizeStep trims titles, collapses inner whitespace
+// and brings them to Unicode normalization form C.
+func NormalizeStep() Step {
+	return func(p Params, places []place.Model) []place.Model {
+		for i := range places {
+			places[i].Title = normalize(places[i].Title)
+			places[i].SubTitle = normalize(places[i].SubTitle)
+		}
+		return places
+	}
+}
+
+func normalize(s string) string {
+	return norm.NFC.String(strings.Join(strings.Fields(s), " "))
+}
+
+// TypesStep drops places of types other than given ones.
+func TypesStep(types ...string) Step {
+	return func(p Params, places []place.Model) []place.Model {
+		result := places[:0]
+		for _, m := range places {
+			if contains(types, m.Type) {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
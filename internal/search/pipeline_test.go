@@ -0,0 +1,72 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/romanyx/places/internal/place"
+)
+
+func TestPipelineProcess(t *testing.T) {
+	tt := []struct {
+		name     string
+		pipeline Pipeline
+		places   []place.Model
+		expect   []place.Model
+	}{
+		{
+			name:   "no steps",
+			places: []place.Model{{Slug: "MOW"}, {Slug: "MOW"}},
+			expect: []place.Model{{Slug: "MOW"}, {Slug: "MOW"}},
+		},
+		{
+			name:     "dedupe",
+			pipeline: Pipeline{DedupeStep()},
+			places: []place.Model{
+				{Slug: "MOW", Type: "city"},
+				{Slug: "mow", Type: "city"},
+				{Slug: "MOW", Type: "airport"},
+			},
+			expect: []place.Model{
+				{Slug: "MOW", Type: "city"},
+				{Slug: "MOW", Type: "airport"},
+			},
+		},
+		{
+			name:     "normalize",
+			pipeline: Pipeline{NormalizeStep()},
+			places: []place.Model{
+				{Title: "  Saint \t Petersburg ", SubTitle: "Russia\n"},
+				{Title: "Zu\u0308rich", SubTitle: "Switzerland"},
+			},
+			expect: []place.Model{
+				{Title: "Saint Petersburg", SubTitle: "Russia"},
+				{Title: "Z\u00fcrich", SubTitle: "Switzerland"},
+			},
+		},
+		{
+			name:     "types",
+			pipeline: Pipeline{TypesStep("city", "airport")},
+			places:   []place.Model{{Slug: "MOW", Type: "city"}, {Slug: "RU", Type: "country"}, {Slug: "SVO", Type: "airport"}},
+			expect:   []place.Model{{Slug: "MOW", Type: "city"}, {Slug: "SVO", Type: "airport"}},
+		},
+		{
+			name:     "several steps",
+			pipeline: Pipeline{NormalizeStep(), DedupeStep()},
+			places:   []place.Model{{Slug: "MOW", Title: "Moscow "}, {Slug: "MOW", Title: "Moscow"}, {Slug: "SVO"}},
+			expect:   []place.Model{{Slug: "MOW", Title: "Moscow"}, {Slug: "SVO"}},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.pipeline.Process(Params{}, tc.places)
+			if !reflect.DeepEqual(tc.expect, got) {
+				t.Errorf("expected: %v got: %v", tc.expect, got)
+			}
+		})
+	}
+}
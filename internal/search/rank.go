@@ -0,0 +1,87 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/romanyx/places/internal/place"
+)
+
+// Ranker orders places by relevance for params.
+type Ranker interface {
+	Rank(Params, []place.Model)
+}
+
+// Scorer scores place for params, the higher
+// score is, the more relevant place is.
+type Scorer func(Params, place.Model) float64
+
+// ScoreRanker orders places by sum of scores, places
+// with equal scores keep their original order.
+type ScoreRanker []Scorer
+
+// Rank implements Ranker.
+func (r ScoreRanker) Rank(p Params, places []place.Model) {
+	if len(r) == 0 || len(places) < 2 {
+		return
+	}
+
+	scores := make([]float64, len(places))
+	for i, m := range places {
+		for _, score := range r {
+			scores[i] += score(p, m)
+		}
+	}
+
+	sort.Stable(byScore{places: places, scores: scores})
+}
+
+type byScore struct {
+	places []place.Model
+	scores []float64
+}
+
+func (s byScore) Len() int           { return len(s.places) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.places[i], s.places[j] = s.places[j], s.places[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
+
+// PrefixScorer boosts places which slug equals to the
+// term or which title starts with the term.
+func PrefixScorer(boost float64) Scorer {
+	return func(p Params, m place.Model) float64 {
+		term := strings.ToLower(strings.TrimSpace(p.Term))
+		if term == "" {
+			return 0
+		}
+		if strings.ToLower(m.Slug) == term || strings.HasPrefix(strings.ToLower(m.Title), term) {
+			return boost
+		}
+		return 0
+	}
+}
+
+// PopularityScorer scores places by their weight
+// on logarithmic scale.
+func PopularityScorer(factor float64) Scorer {
+	return func(p Params, m place.Model) float64 {
+		if m.Weight <= 0 {
+			return 0
+		}
+		return factor * math.Log10(float64(m.Weight)+1)
+	}
+}
+
+// MarketScorer boosts places located in the home
+// market country.
+func MarketScorer(country string, boost float64) Scorer {
+	return func(p Params, m place.Model) float64 {
+		if country != "" && strings.EqualFold(m.CountryCode, country) {
+			return boost
+		}
+		return 0
+	}
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/romanyx/places/internal/place"
+)
+
+func TestScoreRankerRank(t *testing.T) {
+	tt := []struct {
+		name   string
+		ranker ScoreRanker
+		places []place.Model
+		expect []string
+	}{
+		{
+			name:   "no scorers",
+			places: []place.Model{{Slug: "LED"}, {Slug: "MOW"}},
+			expect: []string{"LED", "MOW"},
+		},
+		{
+			name:   "prefix",
+			ranker: ScoreRanker{PrefixScorer(1)},
+			places: []place.Model{{Slug: "LED", Title: "Saint Petersburg"}, {Slug: "MOW", Title: "Moscow"}},
+			expect: []string{"MOW", "LED"},
+		},
+		{
+			name:   "popularity",
+			ranker: ScoreRanker{PopularityScorer(1)},
+			places: []place.Model{{Slug: "DME", Weight: 10}, {Slug: "MOW", Weight: 1000}, {Slug: "SVO", Weight: 100}},
+			expect: []string{"MOW", "SVO", "DME"},
+		},
+		{
+			name:   "market",
+			ranker: ScoreRanker{MarketScorer("ru", 1)},
+			places: []place.Model{{Slug: "MLW", CountryCode: "LR"}, {Slug: "MOW", CountryCode: "RU"}, {Slug: "MRV", CountryCode: "RU"}},
+			expect: []string{"MOW", "MRV", "MLW"},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.ranker.Rank(Params{Term: "mo"}, tc.places)
+
+			got := make([]string, len(tc.places))
+			for i := range tc.places {
+				got[i] = tc.places[i].Slug
+			}
+			if !reflect.DeepEqual(tc.expect, got) {
+				t.Errorf("expected: %v got: %v", tc.expect, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package search
 
 import (
 	"context"
+	"time"
 
 	"go.opencensus.io/trace"
 
@@ -24,7 +25,7 @@ func NewRepositoryWithTrace(repository Repository) Repository {
 
 // Cache decoraters cache method.
 func (s *RepositoryWithTrace) Cache(ctx context.Context, p Params, places []place.Model) error {
-	_, span := trace.StartSpan(ctx, "repository.cache")
+	ctx, span := trace.StartSpan(ctx, "repository.cache")
 	var err error
 
 	defer func() {
@@ -43,10 +44,10 @@ func (s *RepositoryWithTrace) Cache(ctx context.Context, p Params, places []plac
 }
 
 // Retrieve decoraters retrieve method.
-func (s *RepositoryWithTrace) Retrieve(ctx context.Context, p Params) ([]place.Model, error) {
-	_, span := trace.StartSpan(ctx, "repository.retrieve")
+func (s *RepositoryWithTrace) Retrieve(ctx context.Context, p Params) (Entry, error) {
+	ctx, span := trace.StartSpan(ctx, "repository.retrieve")
 	var err error
-	var places []place.Model
+	var entry Entry
 
 	defer func() {
 		if err != nil {
@@ -59,6 +60,129 @@ func (s *RepositoryWithTrace) Retrieve(ctx context.Context, p Params) ([]place.M
 		span.End()
 	}()
 
-	places, err = s.base.Retrieve(ctx, p)
-	return places, err
+	entry, err = s.base.Retrieve(ctx, p)
+	return entry, err
+}
+
+// CacheNegative decoraters cache negative method.
+func (s *RepositoryWithTrace) CacheNegative(ctx context.Context, p Params, negative Negative, ttl time.Duration) error {
+	ctx, span := trace.StartSpan(ctx, "repository.cache_negative")
+	var err error
+
+	defer func() {
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			span.End()
+			return
+		}
+
+		span.SetStatus(trace.Status{Code: trace.StatusCodeOK, Message: "cache negative successed"})
+		span.End()
+	}()
+
+	err = s.base.CacheNegative(ctx, p, negative, ttl)
+	return err
+}
+
+// RetrieveNegative decoraters retrieve negative method.
+func (s *RepositoryWithTrace) RetrieveNegative(ctx context.Context, p Params) (Negative, error) {
+	ctx, span := trace.StartSpan(ctx, "repository.retrieve_negative")
+	var err error
+	var negative Negative
+
+	defer func() {
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			span.End()
+			return
+		}
+
+		span.SetStatus(trace.Status{Code: trace.StatusCodeOK, Message: "retrieve negative successed"})
+		span.End()
+	}()
+
+	negative, err = s.base.RetrieveNegative(ctx, p)
+	return negative, err
+}
+
+// IndexPlaces decoraters index places method.
+func (s *RepositoryWithTrace) IndexPlaces(ctx context.Context, locale string, places []place.Model) error {
+	ctx, span := trace.StartSpan(ctx, "repository.index_places")
+	var err error
+
+	defer func() {
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			span.End()
+			return
+		}
+
+		span.SetStatus(trace.Status{Code: trace.StatusCodeOK, Message: "index places successed"})
+		span.End()
+	}()
+
+	err = s.base.IndexPlaces(ctx, locale, places)
+	return err
+}
+
+// RetrievePlace decoraters retrieve place method.
+func (s *RepositoryWithTrace) RetrievePlace(ctx context.Context, slug, locale string) (place.Model, error) {
+	ctx, span := trace.StartSpan(ctx, "repository.retrieve_place")
+	var err error
+	var model place.Model
+
+	defer func() {
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			span.End()
+			return
+		}
+
+		span.SetStatus(trace.Status{Code: trace.StatusCodeOK, Message: "retrieve place successed"})
+		span.End()
+	}()
+
+	model, err = s.base.RetrievePlace(ctx, slug, locale)
+	return model, err
+}
+
+// List decoraters list method.
+func (s *RepositoryWithTrace) List(ctx context.Context) ([]Entry, error) {
+	ctx, span := trace.StartSpan(ctx, "repository.list")
+	var err error
+	var entries []Entry
+
+	defer func() {
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			span.End()
+			return
+		}
+
+		span.SetStatus(trace.Status{Code: trace.StatusCodeOK, Message: "list successed"})
+		span.End()
+	}()
+
+	entries, err = s.base.List(ctx)
+	return entries, err
+}
+
+// Delete decoraters delete method.
+func (s *RepositoryWithTrace) Delete(ctx context.Context, p Params) error {
+	ctx, span := trace.StartSpan(ctx, "repository.delete")
+	var err error
+
+	defer func() {
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			span.End()
+			return
+		}
+
+		span.SetStatus(trace.Status{Code: trace.StatusCodeOK, Message: "delete successed"})
+		span.End()
+	}()
+
+	err = s.base.Delete(ctx, p)
+	return err
 }
@@ -2,11 +2,14 @@ package search
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
 
+	"github.com/romanyx/places/internal/broker"
 	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/tenant"
 )
 
 // RequesterWithTrace decorates requester with trace.
@@ -26,13 +29,35 @@ func NewRequesterWithTrace(requester Requester) Requester {
 // Request decoraters search method.
 func (s *RequesterWithTrace) Request(ctx context.Context, p Params) ([]place.Model, error) {
 	_, span := trace.StartSpan(ctx, "requester.request")
+	if t := tenant.FromContext(ctx); t != nil {
+		span.AddAttributes(trace.StringAttribute("tenant", t.ID))
+	}
 	var err error
 	var places []place.Model
 
 	defer func() {
 		if err != nil {
-			switch errors.Cause(err) {
-			case context.DeadlineExceeded, context.Canceled:
+			var upstream *broker.Error
+			if errors.As(err, &upstream) {
+				span.AddAttributes(
+					trace.Int64Attribute("upstream.status", int64(upstream.Status)),
+					trace.Int64Attribute("upstream.retry_after", int64(upstream.RetryAfter/time.Second)),
+				)
+			}
+
+			switch {
+			case errors.Is(err, context.Canceled):
+				span.SetStatus(trace.Status{Code: trace.StatusCodeCancelled, Message: err.Error()})
+			case errors.Is(err, broker.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+				span.SetStatus(trace.Status{Code: trace.StatusCodeDeadlineExceeded, Message: err.Error()})
+			case errors.Is(err, broker.ErrRateLimited), errors.Is(err, ErrSaturated):
+				span.SetStatus(trace.Status{Code: trace.StatusCodeResourceExhausted, Message: err.Error()})
+			case errors.Is(err, broker.ErrUnavailable):
+				span.SetStatus(trace.Status{Code: trace.StatusCodeUnavailable, Message: err.Error()})
+			case errors.Is(err, broker.ErrMalformed):
+				span.SetStatus(trace.Status{Code: trace.StatusCodeDataLoss, Message: err.Error()})
+			case errors.Is(err, broker.ErrBadRequest):
+				span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
 			default:
 				span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
 			}
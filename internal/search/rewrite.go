@@ -0,0 +1,350 @@
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/recovery"
+	"github.com/romanyx/places/internal/tenant"
+)
+
+const (
+	// maxRewrites is a maximum number of rewritten
+	// terms requested for one search.
+	maxRewrites = 3
+	// maxRewriteRequests is a maximum number of upstream
+	// requests of rewritten terms for one search, terms
+	// are requested in every fallback locale.
+	maxRewriteRequests = 4
+	// minFuzzyTerm is a minimum length of term in runes
+	// which is matched with edit distance, shorter terms
+	// are close to too many names.
+	minFuzzyTerm = 4
+	// indexListInterval is a minimum interval between
+	// listings of cache by index dictionary.
+	indexListInterval = time.Minute
+	// indexListTimeout is a timeout of cache listing.
+	indexListTimeout = 5 * time.Second
+)
+
+// Rewriter suggests terms to retry search with,
+// when requested term gave no places.
+type Rewriter interface {
+	Rewrite(context.Context, Params) []string
+}
+
+// ChainRewriter joins suggestions of rewriters in order,
+// rewriters receive the original params.
+type ChainRewriter []Rewriter
+
+// Rewrite implements Rewriter.
+func (c ChainRewriter) Rewrite(ctx context.Context, p Params) []string {
+	var terms []string
+	for _, r := range c {
+		terms = append(terms, r.Rewrite(ctx, p)...)
+	}
+	return terms
+}
+
+// TranslitRewriter suggests term transliterated from Cyrillic
+// to Latin or from Latin to Cyrillic, depending on the script
+// which term is written in.
+type TranslitRewriter struct{}
+
+// Rewrite implements Rewriter.
+func (TranslitRewriter) Rewrite(ctx context.Context, p Params) []string {
+	term := strings.ToLower(strings.TrimSpace(p.Term))
+	var result string
+	switch {
+	case hasScript(term, unicode.Cyrillic):
+		result = toLatin(term)
+	case hasScript(term, unicode.Latin):
+		result = toCyrillic(term)
+	}
+
+	if result == "" || result == term {
+		return nil
+	}
+	return []string{result}
+}
+
+func hasScript(s string, script *unicode.RangeTable) bool {
+	for _, r := range s {
+		if unicode.Is(script, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// cyrillicToLatin transliterates russian letters.
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e",
+	'ё': "e", 'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k",
+	'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// latinToCyrillic transliterates latin letters and letter
+// combinations, combinations are matched first.
+var latinToCyrillic = []struct {
+	latin, cyrillic string
+}{
+	{"shch", "щ"}, {"zh", "ж"}, {"kh", "х"}, {"ts", "ц"},
+	{"ch", "ч"}, {"sh", "ш"}, {"yu", "ю"}, {"ya", "я"},
+	{"yo", "ё"}, {"ye", "е"}, {"a", "а"}, {"b", "б"},
+	{"c", "к"}, {"d", "д"}, {"e", "е"}, {"f", "ф"},
+	{"g", "г"}, {"h", "х"}, {"i", "и"}, {"j", "дж"},
+	{"k", "к"}, {"l", "л"}, {"m", "м"}, {"n", "н"},
+	{"o", "о"}, {"p", "п"}, {"q", "к"}, {"r", "р"},
+	{"s", "с"}, {"t", "т"}, {"u", "у"}, {"v", "в"},
+	{"w", "в"}, {"x", "кс"}, {"y", "ы"}, {"z", "з"},
+}
+
+func toLatin(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func toCyrillic(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		var matched bool
+		for _, t := range latinToCyrillic {
+			if strings.HasPrefix(s, t.latin) {
+				b.WriteString(t.cyrillic)
+				s = s[len(t.latin):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		b.WriteRune(r)
+		s = s[size:]
+	}
+	return b.String()
+}
+
+// Dictionary provides names of places known locally
+// to the tenant stored in ctx.
+type Dictionary interface {
+	Names(ctx context.Context, locale string) []string
+}
+
+// Lister lists cached entries.
+type Lister interface {
+	List(context.Context) ([]Entry, error)
+}
+
+// IndexDictionary provides titles of places cached locally,
+// it corrects terms when there is no dataset. Cache is listed
+// in background at most once per interval, since listing scans
+// all of it, names of the previous listing are served meanwhile
+// and kept when it fails. Cache of every tenant is listed
+// separately, since tenants have own cache namespaces.
+type IndexDictionary struct {
+	lister   Lister
+	interval time.Duration
+
+	mu      sync.Mutex
+	tenants map[string]*indexNames
+}
+
+// indexNames contains names of places cached by tenant.
+type indexNames struct {
+	tenant *tenant.Tenant
+	// names holds map[string][]string of names by locale.
+	names atomic.Value
+
+	listedAt time.Time
+	listing  bool
+}
+
+// NewIndexDictionary returns dictionary of places listed
+// by lister.
+func NewIndexDictionary(lister Lister, interval time.Duration) *IndexDictionary {
+	return &IndexDictionary{
+		lister:   lister,
+		interval: interval,
+		tenants:  make(map[string]*indexNames),
+	}
+}
+
+// Names implements Dictionary.
+func (d *IndexDictionary) Names(ctx context.Context, locale string) []string {
+	n := d.refresh(tenant.FromContext(ctx))
+	names, _ := n.names.Load().(map[string][]string)
+	return names[locale]
+}
+
+// refresh returns names of the tenant and starts listing
+// of its cache when interval passed since the previous one,
+// so searches do not wait for it.
+func (d *IndexDictionary) refresh(t *tenant.Tenant) *indexNames {
+	var id string
+	if t != nil {
+		id = t.ID
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n, ok := d.tenants[id]
+	if !ok {
+		n = &indexNames{tenant: t}
+		d.tenants[id] = n
+	}
+	if n.listing || time.Since(n.listedAt) < d.interval {
+		return n
+	}
+	n.listing = true
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), indexListTimeout)
+		defer cancel()
+		if n.tenant != nil {
+			ctx = tenant.NewContext(ctx, n.tenant)
+		}
+		defer recovery.Recover(ctx, "index_dictionary")
+
+		names, err := d.list(ctx)
+		if err != nil {
+			log.Warn(errors.Wrap(err, "list index names"), map[string]interface{}{
+				"tenant": id,
+			})
+		} else {
+			n.names.Store(names)
+		}
+
+		d.mu.Lock()
+		n.listing = false
+		n.listedAt = time.Now()
+		d.mu.Unlock()
+	}()
+	return n
+}
+
+func (d *IndexDictionary) list(ctx context.Context) (map[string][]string, error) {
+	entries, err := d.lister.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string][]string)
+	seen := make(map[string]map[string]struct{})
+	for _, e := range entries {
+		locale := e.Params.Locale
+		if seen[locale] == nil {
+			seen[locale] = make(map[string]struct{})
+		}
+		for _, m := range e.Places {
+			if _, ok := seen[locale][m.Title]; ok || m.Title == "" {
+				continue
+			}
+			seen[locale][m.Title] = struct{}{}
+			names[locale] = append(names[locale], m.Title)
+		}
+	}
+	return names, nil
+}
+
+// FuzzyRewriter suggests names from dictionary which are
+// within MaxDistance edits from term, closest first.
+type FuzzyRewriter struct {
+	Dictionary  Dictionary
+	MaxDistance int
+}
+
+// Rewrite implements Rewriter.
+func (f FuzzyRewriter) Rewrite(ctx context.Context, p Params) []string {
+	term := []rune(strings.ToLower(strings.TrimSpace(p.Term)))
+	if len(term) < minFuzzyTerm {
+		return nil
+	}
+
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	for _, name := range f.Dictionary.Names(ctx, p.Locale) {
+		candidate := []rune(strings.ToLower(name))
+		if abs(len(candidate)-len(term)) > f.MaxDistance {
+			continue
+		}
+		d := distance(term, candidate)
+		if d == 0 || d > f.MaxDistance {
+			continue
+		}
+		matches = append(matches, match{name: name, distance: d})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+	terms := make([]string, 0, len(matches))
+	for _, m := range matches {
+		terms = append(terms, m.name)
+	}
+	return terms
+}
+
+// distance returns Levenshtein distance between a and b.
+func distance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,203 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/tenant"
+)
+
+func TestTranslitRewriterRewrite(t *testing.T) {
+	tt := []struct {
+		name   string
+		term   string
+		expect []string
+	}{
+		{name: "cyrillic", term: "Москва", expect: []string{"moskva"}},
+		{name: "cyrillic combinations", term: "Щучье", expect: []string{"shchuche"}},
+		{name: "latin", term: "Moskva", expect: []string{"москва"}},
+		{name: "latin combinations", term: "Khabarovsk", expect: []string{"хабаровск"}},
+		{name: "no letters", term: "123"},
+		{name: "empty"},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := TranslitRewriter{}.Rewrite(context.Background(), Params{Term: tc.term})
+			if !reflect.DeepEqual(tc.expect, got) {
+				t.Errorf("expected: %v got: %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestFuzzyRewriterRewrite(t *testing.T) {
+	dictionary := dictionaryFunc(func(ctx context.Context, locale string) []string {
+		return []string{"Moscow", "Mosul", "Paris", "Moscow Mills"}
+	})
+
+	tt := []struct {
+		name   string
+		term   string
+		expect []string
+	}{
+		{name: "one edit", term: "Moscw", expect: []string{"Moscow", "Mosul"}},
+		{name: "closest first", term: "mosol", expect: []string{"Mosul", "Moscow"}},
+		{name: "exact match", term: "paris"},
+		{name: "too far", term: "London"},
+		{name: "too short", term: "Par"},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := FuzzyRewriter{Dictionary: dictionary, MaxDistance: 2}
+			got := r.Rewrite(context.Background(), Params{Term: tc.term})
+			if len(tc.expect) == 0 && len(got) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(tc.expect, got) {
+				t.Errorf("expected: %v got: %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tt := []struct {
+		a, b   string
+		expect int
+	}{
+		{a: "", b: "", expect: 0},
+		{a: "moscow", b: "", expect: 6},
+		{a: "moscow", b: "moscw", expect: 1},
+		{a: "kitten", b: "sitting", expect: 3},
+		{a: "москва", b: "масква", expect: 1},
+	}
+
+	for _, tc := range tt {
+		if got := distance([]rune(tc.a), []rune(tc.b)); got != tc.expect {
+			t.Errorf("distance(%q, %q) expected: %d got: %d", tc.a, tc.b, tc.expect, got)
+		}
+	}
+}
+
+func TestIndexDictionaryNames(t *testing.T) {
+	var (
+		listed int32
+		err    atomic.Value
+	)
+	lister := listerFunc(func(ctx context.Context) ([]Entry, error) {
+		atomic.AddInt32(&listed, 1)
+		fail, _ := err.Load().(error)
+		if fail != nil {
+			return nil, fail
+		}
+		return []Entry{
+			{Params: Params{Term: "mos", Locale: "en"}, Places: []place.Model{{Title: "Moscow"}, {Title: "Mosul"}}},
+			{Params: Params{Term: "moscow", Locale: "en"}, Places: []place.Model{{Title: "Moscow"}}},
+			{Params: Params{Term: "мос", Locale: "ru"}, Places: []place.Model{{Title: "Москва"}}},
+		}, nil
+	})
+
+	ctx := context.Background()
+	d := NewIndexDictionary(lister, time.Hour)
+	// Cache is listed in background, so there are
+	// no names until the first listing is done.
+	if got := d.Names(ctx, "en"); got != nil {
+		t.Errorf("expected no names got: %v", got)
+	}
+	waitListed(t, d, "")
+
+	expect := []string{"Moscow", "Mosul"}
+	if got := d.Names(ctx, "en"); !reflect.DeepEqual(expect, got) {
+		t.Errorf("expected: %v got: %v", expect, got)
+	}
+	if got := d.Names(ctx, "ru"); !reflect.DeepEqual([]string{"Москва"}, got) {
+		t.Errorf("expected: %v got: %v", []string{"Москва"}, got)
+	}
+	if got := atomic.LoadInt32(&listed); got != 1 {
+		t.Errorf("expected cache to be listed once within interval got: %d", got)
+	}
+
+	// Names of the previous listing are kept on failure.
+	d.mu.Lock()
+	d.tenants[""].listedAt = time.Time{}
+	d.mu.Unlock()
+	err.Store(errors.New("unexpected error"))
+	d.Names(ctx, "en")
+	waitListed(t, d, "")
+	if got := d.Names(ctx, "en"); !reflect.DeepEqual(expect, got) {
+		t.Errorf("expected: %v got: %v", expect, got)
+	}
+	if got := atomic.LoadInt32(&listed); got != 2 {
+		t.Errorf("expected cache to be listed after interval got: %d", got)
+	}
+}
+
+func TestIndexDictionaryNamesTenant(t *testing.T) {
+	lister := listerFunc(func(ctx context.Context) ([]Entry, error) {
+		if tenant.Prefix(ctx) == "tenant:acme:" {
+			return []Entry{{Places: []place.Model{{Title: "Mosul"}}}}, nil
+		}
+		return []Entry{{Places: []place.Model{{Title: "Moscow"}}}}, nil
+	})
+
+	d := NewIndexDictionary(lister, time.Hour)
+	ctx := context.Background()
+	acmeCtx := tenant.NewContext(ctx, &tenant.Tenant{ID: "acme"})
+	d.Names(ctx, "")
+	d.Names(acmeCtx, "")
+	waitListed(t, d, "")
+	waitListed(t, d, "acme")
+
+	if got := d.Names(ctx, ""); !reflect.DeepEqual([]string{"Moscow"}, got) {
+		t.Errorf("expected: %v got: %v", []string{"Moscow"}, got)
+	}
+	if got := d.Names(acmeCtx, ""); !reflect.DeepEqual([]string{"Mosul"}, got) {
+		t.Errorf("expected: %v got: %v", []string{"Mosul"}, got)
+	}
+}
+
+// waitListed waits until background listing of tenant
+// cache by d is done.
+func waitListed(t *testing.T, d *IndexDictionary, id string) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		d.mu.Lock()
+		n, ok := d.tenants[id]
+		done := ok && !n.listing && !n.listedAt.IsZero()
+		d.mu.Unlock()
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected cache to be listed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type listerFunc func(context.Context) ([]Entry, error)
+
+func (f listerFunc) List(ctx context.Context) ([]Entry, error) {
+	return f(ctx)
+}
+
+type dictionaryFunc func(ctx context.Context, locale string) []string
+
+func (f dictionaryFunc) Names(ctx context.Context, locale string) []string {
+	return f(ctx, locale)
+}
@@ -2,6 +2,7 @@ package search
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -10,7 +11,9 @@ import (
 	"github.com/romanyx/places/internal/broker"
 	"github.com/romanyx/places/internal/log"
 	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/recovery"
 	"github.com/romanyx/places/internal/storage"
+	"github.com/romanyx/places/internal/tenant"
 )
 
 //go:generate mockgen -package=search -destination=service.mock_test.go -source=service.go Repository
@@ -18,12 +21,62 @@ import (
 var (
 	// ErrUnavailable returns when request failed and cache not found.
 	ErrUnavailable = errors.New("places unavailable")
+	// ErrOverloaded returns when requester is saturated and
+	// cache not found.
+	ErrOverloaded = errors.New("places overloaded")
 )
 
+// UnavailableError returns when request failed and cache
+// not found, it keeps error of the request so caller can
+// tell what happened to aviasales. It is ErrUnavailable
+// for errors.Is.
+type UnavailableError struct {
+	Err error
+}
+
+func (e *UnavailableError) Error() string {
+	return ErrUnavailable.Error() + ": " + e.Err.Error()
+}
+
+// Is reports whether target is ErrUnavailable.
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// Unwrap returns error of the request.
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Negative is a kind of negative cache entry.
+type Negative int
+
+const (
+	// NegativeEmpty marks params for which aviasales
+	// returned no places.
+	NegativeEmpty Negative = iota + 1
+	// NegativeBadRequest marks params for which aviasales
+	// responded with bad request status.
+	NegativeBadRequest
+)
+
+// Entry represents cached places.
+type Entry struct {
+	Params   Params
+	Places   []place.Model
+	CachedAt time.Time
+}
+
 // Repository is a data access layer.
 type Repository interface {
 	Cache(context.Context, Params, []place.Model) error
-	Retrieve(context.Context, Params) ([]place.Model, error)
+	Retrieve(context.Context, Params) (Entry, error)
+	CacheNegative(context.Context, Params, Negative, time.Duration) error
+	RetrieveNegative(context.Context, Params) (Negative, error)
+	IndexPlaces(ctx context.Context, locale string, places []place.Model) error
+	RetrievePlace(ctx context.Context, slug, locale string) (place.Model, error)
+	List(context.Context) ([]Entry, error)
+	Delete(context.Context, Params) error
 }
 
 // Requester requests aviasales places endpoint.
@@ -31,29 +84,126 @@ type Requester interface {
 	Request(context.Context, Params) ([]place.Model, error)
 }
 
+// Option configures service.
+type Option func(*Service)
+
+// WithNegativeTTL enables negative cache of empty and bad
+// request results, which will be stored for a given ttl.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.negativeTTL = ttl
+	}
+}
+
+// WithFallback sets requester which will be used as the
+// last resort, when request failed and cache not found.
+func WithFallback(fallback Requester) Option {
+	return func(s *Service) {
+		s.fallback = fallback
+	}
+}
+
+// WithLocales sets locale fallbacks, which will be used
+// when there are no results for requested locale.
+func WithLocales(locales Locales) Option {
+	return func(s *Service) {
+		s.locales = locales
+	}
+}
+
+// WithRanker sets ranker which orders places both
+// requested and retrieved from cache.
+func WithRanker(ranker Ranker) Option {
+	return func(s *Service) {
+		s.ranker = ranker
+	}
+}
+
+// WithPipeline sets pipeline which processes requested
+// places before they are ranked and cached.
+func WithPipeline(pipeline Pipeline) Option {
+	return func(s *Service) {
+		s.pipeline = pipeline
+	}
+}
+
+// WithMaxResults sets maximum number of places in result,
+// it is enforced after places are ranked, so the best
+// places are kept. Zero disables it.
+func WithMaxResults(n int) Option {
+	return func(s *Service) {
+		s.maxResults = n
+	}
+}
+
+// WithRewriter sets rewriter which suggests terms to retry
+// request with, when requested term gave no places.
+func WithRewriter(rewriter Rewriter) Option {
+	return func(s *Service) {
+		s.rewriter = rewriter
+	}
+}
+
+// WithIndexRewriter adds rewriter which corrects terms with
+// titles of places cached in repository of the service, it
+// is used when there is no dataset. It follows rewriter set
+// by WithRewriter.
+func WithIndexRewriter(maxDistance int) Option {
+	return func(s *Service) {
+		s.indexRewrite = true
+		s.indexDistance = maxDistance
+	}
+}
+
 // NewService initialize search service.
-func NewService(rq Requester, repo Repository, timeout time.Duration) *Service {
+func NewService(rq Requester, repo Repository, timeout time.Duration, opts ...Option) *Service {
 	s := Service{
 		Requester:  rq,
 		Repository: repo,
 		timeout:    timeout,
 	}
 
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	if s.indexRewrite {
+		fuzzy := FuzzyRewriter{
+			Dictionary:  NewIndexDictionary(s.Repository, indexListInterval),
+			MaxDistance: s.indexDistance,
+		}
+		if s.rewriter == nil {
+			s.rewriter = fuzzy
+		} else {
+			s.rewriter = ChainRewriter{s.rewriter, fuzzy}
+		}
+	}
+
 	return &s
 }
 
 // Params represents params by which search will be done.
 type Params struct {
-	Term   string   `url:"term"`
-	Locale string   `url:"locale"`
-	Types  []string `url:"types"`
+	Term   string   `url:"term" json:"term"`
+	Locale string   `url:"locale" json:"locale"`
+	Types  []string `url:"types" json:"types"`
 }
 
 // Service contains domain logic for finding process.
 type Service struct {
 	Requester
 	Repository
-	timeout time.Duration
+	timeout     time.Duration
+	negativeTTL time.Duration
+	fallback    Requester
+	locales     Locales
+	ranker      Ranker
+	pipeline    Pipeline
+	maxResults  int
+	rewriter    Rewriter
+
+	indexRewrite  bool
+	indexDistance int
 }
 
 // Search searches place in aviasales. It will try to
@@ -61,7 +211,16 @@ type Service struct {
 // try to retieve cache and return it, otherwise will
 // save cache of the request and return result.
 // If request will fail or timeout and there would not be
-// any cache in storage will return ErrUnavailable.
+// any cache in storage will try fallback requester if it
+// is set and otherwise will return UnavailableError.
+// When negative cache is enabled empty and bad request
+// results are answered from it without request.
+// When locale fallbacks are set both request and cache
+// retrieve go through the chain of locales until
+// they find any places.
+// When rewriter is set and term gives no places, request
+// is retried with rewritten terms and the first one which
+// gives places is stored in meta and cached instead.
 //
 // TODO(romanyx): Think about idea that first step should be
 // cache retrieve and only then, if cache found - there should
@@ -72,26 +231,48 @@ func (s *Service) Search(ctx context.Context, p Params) ([]place.Model, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	places, err := s.Request(ctx, p)
+	if s.negativeTTL > 0 {
+		negative, err := s.RetrieveNegative(ctx, p)
+		switch {
+		case err == nil:
+			setCached(ctx)
+			return negativeResult(negative)
+		case errors.Cause(err) != storage.ErrCacheNotFound:
+			log.Error(errors.Wrap(err, "unexpected error on retrieve negative"), map[string]interface{}{
+				"trace_id": spanCtx.TraceID,
+			})
+		}
+	}
+
+	places, locale, err := s.requestLocales(ctx, p)
 	if err != nil {
 		// Log unexpected error.
-		switch errors.Cause(err) {
-		case context.DeadlineExceeded:
-			// Retrieve places from cache if request deadline exceeded.
-		case context.Canceled:
+		switch {
+		case errors.Is(err, context.Canceled):
 			// Return when cancelled no need to process futher.
 			return places, nil
-		case broker.ErrBadRequest:
+		case errors.Is(err, broker.ErrBadRequest):
 			// When aviasales server returns bad request show it.
+			s.cacheNegative(ctx, p, NegativeBadRequest)
 			return nil, err
+		case errors.Is(err, broker.ErrTimeout), errors.Is(err, context.DeadlineExceeded),
+			errors.Is(err, broker.ErrRateLimited), errors.Is(err, ErrSaturated):
+			// Retrieve places from cache if request deadline exceeded
+			// or there is no capacity for request.
 		default:
 			log.Warn(errors.Wrap(err, "unexpected error on request"), map[string]interface{}{
 				"trace_id": spanCtx.TraceID,
 			})
 		}
 
-		// Continue to retrive cache.
-		places, err = s.Retrieve(ctx, p)
+		// Continue to retrive cache. Deadline of ctx is likely
+		// exceeded by the request, so cache is read on detached
+		// context, otherwise storage rejects the read.
+		requestErr := err
+		retrieveCtx, cancel := s.detach(ctx)
+		defer cancel()
+		var entry Entry
+		entry, err = s.retrieveLocales(retrieveCtx, p)
 		if err != nil {
 			if errors.Cause(err) != storage.ErrCacheNotFound {
 				// Log error only if it is unexpected cache not found is
@@ -100,22 +281,241 @@ func (s *Service) Search(ctx context.Context, p Params) ([]place.Model, error) {
 					"trace_id": spanCtx.TraceID,
 				})
 			}
-			return nil, ErrUnavailable
+			if places, ok := s.requestFallback(ctx, p); ok {
+				return s.process(p, places), nil
+			}
+			if errors.Is(requestErr, ErrSaturated) {
+				return nil, ErrOverloaded
+			}
+			return nil, &UnavailableError{Err: requestErr}
 		}
+		setStale(ctx, entry.CachedAt)
+		places = entry.Places
+		s.rank(p, places)
+		return s.limit(places), nil
+	}
+
+	original, corrected := p, false
+	if len(places) == 0 {
+		if rp, rewritten, ok := s.rewrite(ctx, p); ok {
+			p, places, corrected = rp, rewritten, true
+		}
+	} else {
+		// Places are cached and indexed under the locale which
+		// answered, so titles of the fallback locale are not
+		// stored as titles of the requested one.
+		p.Locale = locale
+	}
+
+	// Process and rank before cache, so cached places are
+	// clean and since cache is saved concurrently.
+	places = s.process(p, places)
+	if len(places) == 0 && s.negativeTTL > 0 {
+		s.cacheNegative(ctx, p, NegativeEmpty)
 		return places, nil
 	}
 
 	// Save cache of request if it was successfull.
 	go func() {
+		ctx, cancel := s.detach(ctx)
+		defer cancel()
+		defer recovery.Recover(ctx, "cache")
+
 		if err := s.Cache(ctx, p, places); err != nil {
 			log.Error(errors.Wrap(err, "cache failed"), map[string]interface{}{
 				"trace_id": spanCtx.TraceID,
 			})
 		}
+		// Original term is cached too, so it is answered
+		// from cache when request of it fails.
+		if corrected {
+			if err := s.Cache(ctx, original, places); err != nil {
+				log.Error(errors.Wrap(err, "cache original failed"), map[string]interface{}{
+					"trace_id": spanCtx.TraceID,
+				})
+			}
+		}
+		if err := s.IndexPlaces(ctx, p.Locale, places); err != nil {
+			log.Error(errors.Wrap(err, "index failed"), map[string]interface{}{
+				"trace_id": spanCtx.TraceID,
+			})
+		}
 		cached()
 	}()
 
 	return places, nil
 }
 
+// process applies pipeline to requested places, ranks
+// them and then enforces maximum number of results.
+func (s *Service) process(p Params, places []place.Model) []place.Model {
+	places = s.pipeline.Process(p, places)
+	s.rank(p, places)
+	return s.limit(places)
+}
+
+func (s *Service) rank(p Params, places []place.Model) {
+	if s.ranker != nil {
+		s.ranker.Rank(p, places)
+	}
+}
+
+func (s *Service) limit(places []place.Model) []place.Model {
+	if s.maxResults > 0 && len(places) > s.maxResults {
+		return places[:s.maxResults]
+	}
+	return places
+}
+
+// rewrite requests terms suggested by rewriter until one
+// of them gives places, returns params of that term.
+func (s *Service) rewrite(ctx context.Context, p Params) (Params, []place.Model, bool) {
+	if s.rewriter == nil {
+		return p, nil, false
+	}
+
+	tried := map[string]struct{}{
+		strings.ToLower(p.Term): {},
+	}
+	var requests int
+	for _, term := range s.rewriter.Rewrite(ctx, p) {
+		if _, ok := tried[strings.ToLower(term)]; ok {
+			continue
+		}
+		if len(tried) > maxRewrites {
+			break
+		}
+		tried[strings.ToLower(term)] = struct{}{}
+
+		for _, locale := range s.locales.Chain(p.Locale) {
+			if requests >= maxRewriteRequests {
+				return p, nil, false
+			}
+			requests++
+
+			rp := p
+			rp.Term = term
+			rp.Locale = locale
+			places, err := s.Request(ctx, rp)
+			if err != nil && !errors.Is(err, broker.ErrBadRequest) {
+				// Original term already succeeded, so empty
+				// result is returned for it.
+				return p, nil, false
+			}
+			if err == nil && len(places) > 0 {
+				setCorrected(ctx, term)
+				return rp, places, true
+			}
+		}
+	}
+
+	return p, nil, false
+}
+
+// requestLocales requests places in the chain of locales
+// and stops on first non empty result. Next locale is tried
+// only if the previous one had no places or was rejected
+// with bad request. Returns locale which answered, or the
+// requested one when none did.
+func (s *Service) requestLocales(ctx context.Context, p Params) ([]place.Model, string, error) {
+	var places []place.Model
+	var err error
+	for _, locale := range s.locales.Chain(p.Locale) {
+		lp := p
+		lp.Locale = locale
+		places, err = s.Request(ctx, lp)
+		if err != nil && !errors.Is(err, broker.ErrBadRequest) {
+			return nil, p.Locale, err
+		}
+		if err == nil && len(places) > 0 {
+			return places, locale, nil
+		}
+	}
+
+	return places, p.Locale, err
+}
+
+// retrieveLocales retrieves cache in the chain of locales.
+func (s *Service) retrieveLocales(ctx context.Context, p Params) (Entry, error) {
+	var err error
+	for _, locale := range s.locales.Chain(p.Locale) {
+		lp := p
+		lp.Locale = locale
+		var entry Entry
+		entry, err = s.Retrieve(ctx, lp)
+		if err == nil {
+			return entry, nil
+		}
+		if errors.Cause(err) != storage.ErrCacheNotFound {
+			return Entry{}, err
+		}
+	}
+
+	return Entry{}, err
+}
+
+// requestFallback requests fallback requester if it is set.
+// Empty result does not count, since dataset may not know
+// the term, so outage is reported instead.
+func (s *Service) requestFallback(ctx context.Context, p Params) ([]place.Model, bool) {
+	if s.fallback == nil {
+		return nil, false
+	}
+
+	places, err := s.fallback.Request(ctx, p)
+	if err != nil {
+		spanCtx := trace.FromContext(ctx).SpanContext()
+		log.Error(errors.Wrap(err, "fallback request failed"), map[string]interface{}{
+			"trace_id": spanCtx.TraceID,
+		})
+		return nil, false
+	}
+	if len(places) == 0 {
+		return nil, false
+	}
+
+	setDegraded(ctx)
+	return places, true
+}
+
+// cacheNegative saves negative cache entry in background.
+func (s *Service) cacheNegative(ctx context.Context, p Params, negative Negative) {
+	if s.negativeTTL <= 0 {
+		return
+	}
+
+	spanCtx := trace.FromContext(ctx).SpanContext()
+	go func() {
+		ctx, cancel := s.detach(ctx)
+		defer cancel()
+		defer recovery.Recover(ctx, "cache_negative")
+
+		if err := s.CacheNegative(ctx, p, negative, s.negativeTTL); err != nil {
+			log.Error(errors.Wrap(err, "cache negative failed"), map[string]interface{}{
+				"trace_id": spanCtx.TraceID,
+			})
+		}
+		cached()
+	}()
+}
+
+// detach returns context for background work which outlives
+// request, it keeps the trace span and tenant of ctx but not
+// its deadline and cancellation.
+func (s *Service) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := trace.NewContext(context.Background(), trace.FromContext(ctx))
+	if t := tenant.FromContext(ctx); t != nil {
+		detached = tenant.NewContext(detached, t)
+	}
+	return context.WithTimeout(detached, s.timeout)
+}
+
+func negativeResult(negative Negative) ([]place.Model, error) {
+	if negative == NegativeBadRequest {
+		return nil, broker.ErrBadRequest
+	}
+
+	return make([]place.Model, 0), nil
+}
+
 var cached = func() {}
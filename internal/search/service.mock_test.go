@@ -7,6 +7,7 @@ import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
 	place "github.com/romanyx/places/internal/place"
+	time "time"
 )
 
 // Mock of Repository interface
@@ -40,9 +41,9 @@ func (_mr *_MockRepositoryRecorder) Cache(arg0, arg1, arg2 interface{}) *gomock.
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Cache", arg0, arg1, arg2)
 }
 
-func (_m *MockRepository) Retrieve(_param0 context.Context, _param1 Params) ([]place.Model, error) {
+func (_m *MockRepository) Retrieve(_param0 context.Context, _param1 Params) (Entry, error) {
 	ret := _m.ctrl.Call(_m, "Retrieve", _param0, _param1)
-	ret0, _ := ret[0].([]place.Model)
+	ret0, _ := ret[0].(Entry)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -51,6 +52,69 @@ func (_mr *_MockRepositoryRecorder) Retrieve(arg0, arg1 interface{}) *gomock.Cal
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Retrieve", arg0, arg1)
 }
 
+func (_m *MockRepository) CacheNegative(_param0 context.Context, _param1 Params, _param2 Negative, _param3 time.Duration) error {
+	ret := _m.ctrl.Call(_m, "CacheNegative", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockRepositoryRecorder) CacheNegative(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CacheNegative", arg0, arg1, arg2, arg3)
+}
+
+func (_m *MockRepository) RetrieveNegative(_param0 context.Context, _param1 Params) (Negative, error) {
+	ret := _m.ctrl.Call(_m, "RetrieveNegative", _param0, _param1)
+	ret0, _ := ret[0].(Negative)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockRepositoryRecorder) RetrieveNegative(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RetrieveNegative", arg0, arg1)
+}
+
+func (_m *MockRepository) IndexPlaces(ctx context.Context, locale string, places []place.Model) error {
+	ret := _m.ctrl.Call(_m, "IndexPlaces", ctx, locale, places)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockRepositoryRecorder) IndexPlaces(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "IndexPlaces", arg0, arg1, arg2)
+}
+
+func (_m *MockRepository) RetrievePlace(ctx context.Context, slug string, locale string) (place.Model, error) {
+	ret := _m.ctrl.Call(_m, "RetrievePlace", ctx, slug, locale)
+	ret0, _ := ret[0].(place.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockRepositoryRecorder) RetrievePlace(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RetrievePlace", arg0, arg1, arg2)
+}
+
+func (_m *MockRepository) List(_param0 context.Context) ([]Entry, error) {
+	ret := _m.ctrl.Call(_m, "List", _param0)
+	ret0, _ := ret[0].([]Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockRepositoryRecorder) List(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "List", arg0)
+}
+
+func (_m *MockRepository) Delete(_param0 context.Context, _param1 Params) error {
+	ret := _m.ctrl.Call(_m, "Delete", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockRepositoryRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Delete", arg0, arg1)
+}
+
 // Mock of Requester interface
 type MockRequester struct {
 	ctrl     *gomock.Controller
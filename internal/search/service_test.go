@@ -2,6 +2,7 @@ package search
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/romanyx/places/internal/broker"
 	"github.com/romanyx/places/internal/place"
 	"github.com/romanyx/places/internal/storage"
+	"github.com/romanyx/places/internal/tenant"
 )
 
 func TestServiceSearch(t *testing.T) {
@@ -17,8 +19,13 @@ func TestServiceSearch(t *testing.T) {
 		name          string
 		requesterFunc func(ctx context.Context, p Params) ([]place.Model, error)
 		repoFunc      func(m *MockRepository)
+		params        Params
+		tenant        *tenant.Tenant
+		opts          []Option
 		cacheResponse bool
 		expectErr     bool
+		// expectCorrected is a term expected in meta.
+		expectCorrected string
 	}{
 		{
 			name: "happy path",
@@ -27,31 +34,168 @@ func TestServiceSearch(t *testing.T) {
 			},
 			repoFunc: func(m *MockRepository) {
 				m.EXPECT().
-					Cache(gomock.Any(), gomock.Any(), gomock.Any()).
+					Cache(aliveContext{}, gomock.Any(), gomock.Any()).
+					Return(nil)
+				m.EXPECT().
+					IndexPlaces(aliveContext{}, gomock.Any(), gomock.Any()).
 					Return(nil)
 			},
 			cacheResponse: true,
 		},
+		{
+			name: "tenant",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return []place.Model{{Slug: "MOW"}}, nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					Cache(keyPrefix("tenant:acme:"), gomock.Any(), gomock.Any()).
+					Return(nil)
+				m.EXPECT().
+					IndexPlaces(keyPrefix("tenant:acme:"), gomock.Any(), gomock.Any()).
+					Return(nil)
+			},
+			tenant:        &tenant.Tenant{ID: "acme"},
+			cacheResponse: true,
+		},
 		{
 			name: "request timeout",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					Retrieve(aliveContext{}, gomock.Any()).
+					Return(Entry{Places: make([]place.Model, 0), CachedAt: time.Now()}, nil)
+			},
+		},
+		{
+			name: "retrieve no cache",
 			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
 				return nil, context.DeadlineExceeded
 			},
 			repoFunc: func(m *MockRepository) {
 				m.EXPECT().
 					Retrieve(gomock.Any(), gomock.Any()).
-					Return(make([]place.Model, 0), nil)
+					Return(Entry{}, storage.ErrCacheNotFound)
 			},
+			expectErr: true,
 		},
 		{
-			name: "retrieve no cache",
+			name: "locale fallback",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				if p.Locale != "ru" {
+					return make([]place.Model, 0), nil
+				}
+				return []place.Model{{Slug: "MOW"}}, nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					Cache(gomock.Any(), Params{Locale: "ru"}, []place.Model{{Slug: "MOW"}}).
+					Return(nil)
+				m.EXPECT().
+					IndexPlaces(gomock.Any(), "ru", []place.Model{{Slug: "MOW"}}).
+					Return(nil)
+			},
+			opts:          []Option{WithLocales(Locales{"": "ru"})},
+			cacheResponse: true,
+		},
+		{
+			name: "pipeline before cache",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return []place.Model{{Slug: "MOW", Title: " Moscow "}, {Slug: "MOW", Title: "Moscow"}}, nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					Cache(gomock.Any(), Params{}, []place.Model{{Slug: "MOW", Title: "Moscow"}}).
+					Return(nil)
+				m.EXPECT().
+					IndexPlaces(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+			},
+			opts:          []Option{WithPipeline(Pipeline{NormalizeStep(), DedupeStep()})},
+			cacheResponse: true,
+		},
+		{
+			name: "max results after rank",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return []place.Model{{Slug: "VKO", Weight: 10}, {Slug: "MOW", Weight: 1000}, {Slug: "LED", Weight: 100}}, nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					Cache(gomock.Any(), Params{}, []place.Model{{Slug: "MOW", Weight: 1000}, {Slug: "LED", Weight: 100}}).
+					Return(nil)
+				m.EXPECT().
+					IndexPlaces(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+			},
+			opts:          []Option{WithRanker(ScoreRanker{PopularityScorer(1)}), WithMaxResults(2)},
+			cacheResponse: true,
+		},
+		{
+			name: "rewrite",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				if p.Term != "moscow" {
+					return make([]place.Model, 0), nil
+				}
+				return []place.Model{{Slug: "MOW"}}, nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					Cache(gomock.Any(), Params{Term: "moscow"}, []place.Model{{Slug: "MOW"}}).
+					Return(nil)
+				m.EXPECT().
+					Cache(gomock.Any(), Params{}, []place.Model{{Slug: "MOW"}}).
+					Return(nil)
+				m.EXPECT().
+					IndexPlaces(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+			},
+			opts: []Option{WithRewriter(rewriterFunc(func(ctx context.Context, p Params) []string {
+				return []string{"moskow", "moscow"}
+			}))},
+			cacheResponse:   true,
+			expectCorrected: "moscow",
+		},
+		{
+			name: "fallback",
 			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
 				return nil, context.DeadlineExceeded
 			},
 			repoFunc: func(m *MockRepository) {
 				m.EXPECT().
 					Retrieve(gomock.Any(), gomock.Any()).
-					Return(nil, storage.ErrCacheNotFound)
+					Return(Entry{}, storage.ErrCacheNotFound)
+			},
+			opts: []Option{WithFallback(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+				return []place.Model{{Slug: "MOW"}}, nil
+			}))},
+		},
+		{
+			name: "empty fallback",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return nil, context.DeadlineExceeded
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					Retrieve(gomock.Any(), gomock.Any()).
+					Return(Entry{}, storage.ErrCacheNotFound)
+			},
+			opts: []Option{WithFallback(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+				return make([]place.Model, 0), nil
+			}))},
+			expectErr: true,
+		},
+		{
+			name: "saturated no cache",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return nil, ErrSaturated
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					Retrieve(gomock.Any(), gomock.Any()).
+					Return(Entry{}, storage.ErrCacheNotFound)
 			},
 			expectErr: true,
 		},
@@ -70,6 +214,64 @@ func TestServiceSearch(t *testing.T) {
 			repoFunc:  func(m *MockRepository) {},
 			expectErr: true,
 		},
+		{
+			name: "negative bad request",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return nil, errors.New("unexpected request")
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrieveNegative(gomock.Any(), gomock.Any()).
+					Return(NegativeBadRequest, nil)
+			},
+			opts:      []Option{WithNegativeTTL(time.Minute)},
+			expectErr: true,
+		},
+		{
+			name: "negative empty",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return nil, errors.New("unexpected request")
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrieveNegative(gomock.Any(), gomock.Any()).
+					Return(NegativeEmpty, nil)
+			},
+			opts: []Option{WithNegativeTTL(time.Minute)},
+		},
+		{
+			name: "cache negative empty",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return make([]place.Model, 0), nil
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrieveNegative(gomock.Any(), gomock.Any()).
+					Return(Negative(0), storage.ErrCacheNotFound)
+				m.EXPECT().
+					CacheNegative(gomock.Any(), gomock.Any(), NegativeEmpty, time.Minute).
+					Return(nil)
+			},
+			opts:          []Option{WithNegativeTTL(time.Minute)},
+			cacheResponse: true,
+		},
+		{
+			name: "cache negative bad request",
+			requesterFunc: func(ctx context.Context, p Params) ([]place.Model, error) {
+				return nil, broker.ErrBadRequest
+			},
+			repoFunc: func(m *MockRepository) {
+				m.EXPECT().
+					RetrieveNegative(gomock.Any(), gomock.Any()).
+					Return(Negative(0), storage.ErrCacheNotFound)
+				m.EXPECT().
+					CacheNegative(gomock.Any(), gomock.Any(), NegativeBadRequest, time.Minute).
+					Return(nil)
+			},
+			opts:          []Option{WithNegativeTTL(time.Minute)},
+			cacheResponse: true,
+			expectErr:     true,
+		},
 	}
 
 	doneChan := make(chan struct{})
@@ -87,11 +289,19 @@ func TestServiceSearch(t *testing.T) {
 			repo := NewMockRepository(ctrl)
 			tc.repoFunc(repo)
 
-			s := NewService(requesterFunc(tc.requesterFunc), repo, time.Second)
+			s := NewService(requesterFunc(tc.requesterFunc), repo, time.Second, tc.opts...)
 
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
-			_, err := s.Search(ctx, Params{})
+			ctx, meta := WithMeta(ctx)
+			if tc.tenant != nil {
+				ctx = tenant.NewContext(ctx, tc.tenant)
+			}
+			_, err := s.Search(ctx, tc.params)
+
+			if meta.Corrected != tc.expectCorrected {
+				t.Errorf("expected corrected: %q got: %q", tc.expectCorrected, meta.Corrected)
+			}
 
 			if tc.cacheResponse {
 				select {
@@ -115,6 +325,143 @@ func TestServiceSearch(t *testing.T) {
 	}
 }
 
+// aliveContext matches context which is not done, background
+// cache must not be cancelled when search returns and stale
+// cache must be read after request deadline exceeded.
+type aliveContext struct{}
+
+func (aliveContext) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	return ok && ctx.Err() == nil
+}
+
+func (aliveContext) String() string {
+	return "is alive context"
+}
+
+// keyPrefix matches context which storage keys are
+// prefixed with, background cache must keep tenant.
+type keyPrefix string
+
+func (k keyPrefix) Matches(x interface{}) bool {
+	ctx, ok := x.(context.Context)
+	return ok && tenant.Prefix(ctx) == string(k)
+}
+
+func (k keyPrefix) String() string {
+	return "has key prefix " + string(k)
+}
+
+func TestServiceSearchUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := NewMockRepository(ctrl)
+	repo.EXPECT().
+		Retrieve(gomock.Any(), gomock.Any()).
+		Return(Entry{}, storage.ErrCacheNotFound)
+
+	upstream := &broker.Error{Kind: broker.ErrRateLimited, Status: 429, RetryAfter: time.Minute}
+	s := NewService(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+		return nil, upstream
+	}), repo, time.Second)
+
+	_, err := s.Search(context.Background(), Params{})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected: %v got: %v", ErrUnavailable, err)
+	}
+	if !errors.Is(err, broker.ErrRateLimited) {
+		t.Errorf("expected: %v got: %v", broker.ErrRateLimited, err)
+	}
+	var got *broker.Error
+	if !errors.As(err, &got) || got.RetryAfter != time.Minute {
+		t.Errorf("expected upstream error to be kept got: %v", err)
+	}
+}
+
+func TestServiceSearchIndexRewrite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := NewMockRepository(ctrl)
+	repo.EXPECT().
+		List(gomock.Any()).
+		Return([]Entry{{Places: []place.Model{{Slug: "MOW", Title: "Moscow"}}}}, nil)
+	repo.EXPECT().
+		Cache(gomock.Any(), Params{Term: "Moscow"}, gomock.Any()).
+		Return(nil)
+	repo.EXPECT().
+		Cache(gomock.Any(), Params{Term: "Moscw"}, gomock.Any()).
+		Return(nil)
+	repo.EXPECT().
+		IndexPlaces(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	// Index rewriter is kept whatever order of options.
+	s := NewService(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+		if p.Term != "Moscow" {
+			return make([]place.Model, 0), nil
+		}
+		return []place.Model{{Slug: "MOW", Title: "Moscow"}}, nil
+	}), repo, time.Second, WithIndexRewriter(2), WithRewriter(rewriterFunc(func(ctx context.Context, p Params) []string {
+		return nil
+	})))
+
+	// Cache is listed in background.
+	deadline := time.Now().Add(3 * time.Second)
+	for len(s.rewriter.Rewrite(context.Background(), Params{Term: "Moscw"})) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected cache to be listed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	doneChan := make(chan struct{}, 1)
+	defer func(prev func()) { cached = prev }(cached)
+	cached = func() {
+		doneChan <- struct{}{}
+	}
+
+	ctx, meta := WithMeta(context.Background())
+	if _, err := s.Search(ctx, Params{Term: "Moscw"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if meta.Corrected != "Moscow" {
+		t.Errorf("expected corrected: %q got: %q", "Moscow", meta.Corrected)
+	}
+
+	select {
+	case <-doneChan:
+	case <-time.After(3 * time.Second):
+		t.Error("expected to cache response")
+	}
+}
+
+func TestServiceRewriteRequests(t *testing.T) {
+	var requests int
+	s := NewService(requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+		requests++
+		return make([]place.Model, 0), nil
+	}), nil, time.Second,
+		WithLocales(Locales{"de": "en", "en": "ru"}),
+		WithRewriter(rewriterFunc(func(ctx context.Context, p Params) []string {
+			return []string{"moskow", "moscow", "moskva", "mosca"}
+		})),
+	)
+
+	_, _, ok := s.rewrite(context.Background(), Params{Term: "moskau", Locale: "de"})
+	if ok {
+		t.Error("expected no rewrite")
+	}
+	if requests != maxRewriteRequests {
+		t.Errorf("expected requests: %d got: %d", maxRewriteRequests, requests)
+	}
+}
+
+type rewriterFunc func(context.Context, Params) []string
+
+func (f rewriterFunc) Rewrite(ctx context.Context, p Params) []string {
+	return f(ctx, p)
+}
+
 type requesterFunc func(context.Context, Params) ([]place.Model, error)
 
 func (f requesterFunc) Request(ctx context.Context, q Params) ([]place.Model, error) {
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"context"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/tenant"
+)
+
+// RequesterPerTenant routes requests to requesters of
+// tenants, so tenants do not share request limits.
+type RequesterPerTenant struct {
+	base    Requester
+	tenants map[string]Requester
+}
+
+// NewRequesterPerTenant initialize router, requests of the
+// default tenant and of tenants without own requester are
+// routed to base.
+func NewRequesterPerTenant(base Requester, tenants map[string]Requester) Requester {
+	s := RequesterPerTenant{
+		base:    base,
+		tenants: tenants,
+	}
+
+	return &s
+}
+
+// Request routes request to requester of the tenant from ctx.
+func (s *RequesterPerTenant) Request(ctx context.Context, p Params) ([]place.Model, error) {
+	if t := tenant.FromContext(ctx); t != nil {
+		if r, ok := s.tenants[t.ID]; ok {
+			return r.Request(ctx, p)
+		}
+	}
+	return s.base.Request(ctx, p)
+}
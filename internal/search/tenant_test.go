@@ -0,0 +1,39 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/tenant"
+)
+
+func TestRequesterPerTenant(t *testing.T) {
+	requester := func(name string) Requester {
+		return requesterFunc(func(ctx context.Context, p Params) ([]place.Model, error) {
+			return []place.Model{{Slug: name}}, nil
+		})
+	}
+	r := NewRequesterPerTenant(requester("default"), map[string]Requester{"acme": requester("acme")})
+
+	tt := []struct {
+		name   string
+		tenant *tenant.Tenant
+		expect string
+	}{
+		{name: "default", expect: "default"},
+		{name: "own requester", tenant: &tenant.Tenant{ID: "acme"}, expect: "acme"},
+		{name: "no own requester", tenant: &tenant.Tenant{ID: "globex"}, expect: "default"},
+	}
+
+	for _, tc := range tt {
+		ctx := context.Background()
+		if tc.tenant != nil {
+			ctx = tenant.NewContext(ctx, tc.tenant)
+		}
+		places, _ := r.Request(ctx, Params{})
+		if places[0].Slug != tc.expect {
+			t.Errorf("%s expected: %s got: %s", tc.name, tc.expect, places[0].Slug)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/romanyx/places/internal/log"
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/storage"
+	"github.com/romanyx/places/internal/tenant"
+)
+
+const (
+	// openTimeout is a time to wait for a lock of
+	// database file held by another process.
+	openTimeout = time.Second
+	// expiryLen is a length of expiration time
+	// prefix of negative records.
+	expiryLen = 8
+)
+
+// Buckets separate kinds of entries.
+var (
+	placesBucket   = []byte("places")
+	negativeBucket = []byte("negative")
+	indexBucket    = []byte("index")
+)
+
+// Open opens or creates database file and initializes
+// repository. Close must be called to release the file.
+func Open(path string) (*Repository, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, errors.Wrap(err, "open database")
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{placesBucket, negativeBucket, indexBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return errors.Wrapf(err, "create bucket %s", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	r := Repository{
+		db:  db,
+		now: time.Now,
+	}
+
+	return &r, nil
+}
+
+// Repository represents embedded bolt storage, it keeps
+// entries encoded the same way redis storage does.
+type Repository struct {
+	db  *bbolt.DB
+	now func() time.Time
+}
+
+// entry is stored representation of cached places.
+type entry struct {
+	Params   search.Params
+	Places   []place.Model
+	CachedAt time.Time
+}
+
+// Close closes database file.
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// Cache caches query in storage.
+func (r *Repository) Cache(ctx context.Context, p search.Params, places []place.Model) error {
+	e := entry{
+		Params:   p,
+		Places:   places,
+		CachedAt: r.now(),
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&e); err != nil {
+		return errors.Wrap(err, "encode gob")
+	}
+
+	return r.put(ctx, placesBucket, paramsKey(ctx, p), buf.Bytes())
+}
+
+// Retrieve retieves cache from storage.
+func (r *Repository) Retrieve(ctx context.Context, p search.Params) (search.Entry, error) {
+	data, err := r.get(ctx, placesBucket, paramsKey(ctx, p))
+	if err != nil {
+		return search.Entry{}, err
+	}
+
+	return decodeEntry(data)
+}
+
+// List lists all cached entries of the tenant.
+func (r *Repository) List(ctx context.Context) ([]search.Entry, error) {
+	prefix := []byte(tenant.Prefix(ctx))
+	var entries []search.Entry
+	err := r.view(ctx, func(tx *bbolt.Tx) error {
+		c := tx.Bucket(placesBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			// Default tenant has no prefix, so entries
+			// of other tenants are skipped.
+			if len(prefix) == 0 && bytes.HasPrefix(k, []byte(tenant.KeyPrefix)) {
+				continue
+			}
+			e, err := decodeEntry(v)
+			if err != nil {
+				// One broken entry must not hide the rest.
+				log.Warn(errors.Wrap(err, "skip entry"), map[string]interface{}{
+					"key": string(k),
+				})
+				continue
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "iterate entries")
+	}
+
+	return entries, nil
+}
+
+// Delete deletes cache of query, its negative entry and its
+// places from slug index of the params locale, so they are
+// not served by lookup. Returns storage.ErrCacheNotFound when
+// there were neither places nor negative entry.
+func (r *Repository) Delete(ctx context.Context, p search.Params) error {
+	key := []byte(paramsKey(ctx, p))
+	return r.update(ctx, func(tx *bbolt.Tx) error {
+		places, negative := tx.Bucket(placesBucket), tx.Bucket(negativeBucket)
+		data := places.Get(key)
+		if data == nil && negative.Get(key) == nil {
+			return storage.ErrCacheNotFound
+		}
+
+		// Broken entry is deleted without its index.
+		if e, err := decodeEntry(data); data != nil && err == nil {
+			index := tx.Bucket(indexBucket)
+			for _, m := range e.Places {
+				if err := index.Delete(placeKey(ctx, m.Slug, p.Locale)); err != nil {
+					return errors.Wrap(err, "delete index key")
+				}
+			}
+		}
+		if err := places.Delete(key); err != nil {
+			return errors.Wrap(err, "delete key")
+		}
+		return errors.Wrap(negative.Delete(key), "delete negative key")
+	})
+}
+
+// CacheNegative caches negative result of query in storage
+// for a given ttl, zero ttl means no expiration.
+func (r *Repository) CacheNegative(ctx context.Context, p search.Params, negative search.Negative, ttl time.Duration) error {
+	var expiry int64
+	if ttl > 0 {
+		expiry = r.now().Add(ttl).UnixNano()
+	}
+
+	value := make([]byte, expiryLen)
+	binary.BigEndian.PutUint64(value, uint64(expiry))
+	value = strconv.AppendInt(value, int64(negative), 10)
+
+	return r.put(ctx, negativeBucket, paramsKey(ctx, p), value)
+}
+
+// RetrieveNegative retrieves negative cache from storage.
+func (r *Repository) RetrieveNegative(ctx context.Context, p search.Params) (search.Negative, error) {
+	value, err := r.get(ctx, negativeBucket, paramsKey(ctx, p))
+	if err != nil {
+		return 0, err
+	}
+	if len(value) < expiryLen || r.expired(value) {
+		return 0, storage.ErrCacheNotFound
+	}
+
+	negative, err := strconv.Atoi(string(value[expiryLen:]))
+	if err != nil {
+		return 0, errors.Wrap(err, "parse negative")
+	}
+
+	return search.Negative(negative), nil
+}
+
+// IndexPlaces saves places in slug index, every slug
+// keeps its place for each locale separately.
+func (r *Repository) IndexPlaces(ctx context.Context, locale string, places []place.Model) error {
+	if len(places) == 0 {
+		return nil
+	}
+
+	return r.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket(indexBucket)
+		for _, m := range places {
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+				return errors.Wrap(err, "encode gob")
+			}
+			if err := b.Put(placeKey(ctx, m.Slug, locale), buf.Bytes()); err != nil {
+				return errors.Wrap(err, "put key")
+			}
+		}
+		return nil
+	})
+}
+
+// RetrievePlace retrieves place from slug index.
+func (r *Repository) RetrievePlace(ctx context.Context, slug, locale string) (place.Model, error) {
+	data, err := r.get(ctx, indexBucket, string(placeKey(ctx, slug, locale)))
+	if err != nil {
+		return place.Model{}, err
+	}
+
+	var model place.Model
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&model); err != nil {
+		return place.Model{}, errors.Wrap(err, "decode gob")
+	}
+
+	return model, nil
+}
+
+// Sweep deletes expired negative entries every
+// interval until ctx is done.
+func (r *Repository) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.sweep(); err != nil {
+			log.Error(errors.Wrap(err, "sweep expired entries"), nil)
+		}
+	}
+}
+
+func (r *Repository) sweep() error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(negativeBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if len(v) >= expiryLen && !r.expired(v) {
+				continue
+			}
+			if err := c.Delete(); err != nil {
+				return errors.Wrap(err, "delete key")
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Repository) expired(value []byte) bool {
+	expiry := int64(binary.BigEndian.Uint64(value[:expiryLen]))
+	return expiry != 0 && r.now().UnixNano() >= expiry
+}
+
+func (r *Repository) put(ctx context.Context, bucket []byte, key string, value []byte) error {
+	err := r.update(ctx, func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), value)
+	})
+	if err != nil {
+		return errors.Wrap(err, "put key")
+	}
+	return nil
+}
+
+// get returns copy of the value, since value returned
+// by bolt is valid only during transaction.
+func (r *Repository) get(ctx context.Context, bucket []byte, key string) ([]byte, error) {
+	var value []byte
+	err := r.view(ctx, func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(bucket).Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "get key")
+	}
+	if value == nil {
+		return nil, storage.ErrCacheNotFound
+	}
+
+	return value, nil
+}
+
+// view runs read transaction unless ctx is done.
+func (r *Repository) view(ctx context.Context, fn func(*bbolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "context")
+	}
+	return r.db.View(fn)
+}
+
+// update runs write transaction unless ctx is done, transaction
+// is rolled back when ctx is done before it is committed.
+func (r *Repository) update(ctx context.Context, fn func(*bbolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "context")
+	}
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return errors.Wrap(ctx.Err(), "context")
+	})
+}
+
+func decodeEntry(data []byte) (search.Entry, error) {
+	var e entry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		return search.Entry{}, errors.Wrap(err, "decode gob")
+	}
+
+	return search.Entry{Params: e.Params, Places: e.Places, CachedAt: e.CachedAt}, nil
+}
+
+// placeKey returns key of place in the index, keys
+// of tenants are prefixed with their ids.
+func placeKey(ctx context.Context, slug, locale string) []byte {
+	return []byte(tenant.Prefix(ctx) + slug + "\x00" + locale)
+}
+
+// paramsKey returns key of params entries, keys
+// of tenants are prefixed with their ids.
+func paramsKey(ctx context.Context, p search.Params) string {
+	return tenant.Prefix(ctx) + hex.EncodeToString([]byte(fmt.Sprint(p)))
+}
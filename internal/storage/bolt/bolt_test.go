@@ -0,0 +1,83 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/storage/storagetest"
+)
+
+func TestRepository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Backend {
+		r, c := prepareRepository(t)
+		return storagetest.Backend{
+			Repository: r,
+			Advance:    c.Advance,
+		}
+	})
+}
+
+func TestRepositorySweep(t *testing.T) {
+	r, c := prepareRepository(t)
+	ctx := context.Background()
+
+	for i, ttl := range []time.Duration{time.Minute, time.Hour, 0} {
+		p := search.Params{Term: string(rune('a' + i))}
+		if err := r.CacheNegative(ctx, p, search.NegativeEmpty, ttl); err != nil {
+			t.Fatalf("cache negative: %v", err)
+		}
+	}
+
+	c.Advance(2 * time.Minute)
+	if err := r.sweep(); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+
+	var n int
+	r.db.View(func(tx *bbolt.Tx) error {
+		n = tx.Bucket(negativeBucket).Stats().KeyN
+		return nil
+	})
+	if n != 2 {
+		t.Errorf("expected 2 negative entries after sweep got: %d", n)
+	}
+}
+
+func prepareRepository(t *testing.T) (*Repository, *clock) {
+	r, err := Open(filepath.Join(t.TempDir(), "places.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+	})
+
+	c := clock{now: time.Now()}
+	r.now = c.Now
+
+	return r, &c
+}
+
+// clock is a manually advanced clock.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
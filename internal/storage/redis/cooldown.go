@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+)
+
+// cooldownKey keeps cooldown of aviasales requests.
+const cooldownKey = "cooldown:upstream"
+
+// startCooldown sets key with ttl unless it already
+// lives longer.
+var startCooldown = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < tonumber(ARGV[1]) then
+	redis.call("SET", KEYS[1], "1", "PX", ARGV[1])
+end
+return 1
+`)
+
+// NewCooldown initializer for cooldown.
+func NewCooldown(client *redis.Client) *Cooldown {
+	c := Cooldown{
+		client: client,
+	}
+
+	return &c
+}
+
+// Cooldown represents cooldown shared by
+// replicas through redis key ttl.
+type Cooldown struct {
+	client *redis.Client
+}
+
+// Until returns end of the cooldown.
+func (c *Cooldown) Until(ctx context.Context) (time.Time, error) {
+	ttl, err := c.client.PTTL(ctx, cooldownKey).Result()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "get ttl")
+	}
+	// Negative ttl means there is no key.
+	if ttl <= 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Now().Add(ttl), nil
+}
+
+// Start starts cooldown for a given duration.
+func (c *Cooldown) Start(ctx context.Context, d time.Duration) error {
+	ms := int64(d / time.Millisecond)
+	if ms <= 0 {
+		return nil
+	}
+	if err := startCooldown.Run(ctx, c.client, []string{cooldownKey}, ms).Err(); err != nil {
+		return errors.Wrap(err, "run script")
+	}
+	return nil
+}
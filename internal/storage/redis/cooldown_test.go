@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCooldown(t *testing.T) {
+	s, client := prepareRedis(t)
+	c := NewCooldown(client)
+	ctx := context.Background()
+
+	until, err := c.Until(ctx)
+	if err != nil {
+		t.Fatalf("until: %v", err)
+	}
+	if !until.IsZero() {
+		t.Errorf("expected no cooldown got: %v", until)
+	}
+
+	if err := c.Start(ctx, time.Minute); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	// Shorter cooldown does not cut the longer one.
+	if err := c.Start(ctx, time.Second); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	until, err = c.Until(ctx)
+	if err != nil {
+		t.Fatalf("until: %v", err)
+	}
+	if remaining := time.Until(until); remaining < 50*time.Second {
+		t.Errorf("expected cooldown about a minute got: %v", remaining)
+	}
+
+	s.FastForward(2 * time.Minute)
+	until, err = c.Until(ctx)
+	if err != nil {
+		t.Fatalf("until: %v", err)
+	}
+	if !until.IsZero() {
+		t.Errorf("expected cooldown to end got: %v", until)
+	}
+}
@@ -7,62 +7,299 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 
+	"github.com/romanyx/places/internal/log"
 	"github.com/romanyx/places/internal/place"
 	"github.com/romanyx/places/internal/search"
 	"github.com/romanyx/places/internal/storage"
+	"github.com/romanyx/places/internal/tenant"
 )
 
+// Key prefixes separate kinds of entries.
+const (
+	negativePrefix = "negative:"
+	placePrefix    = "place:"
+)
+
+// Option configures repository.
+type Option func(*Repository)
+
+// WithTimeout sets timeout of every storage operation,
+// it bounds redis command even if ctx has no deadline.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Repository) {
+		r.timeout = d
+	}
+}
+
 // NewRepository initializer for repository.
-func NewRepository(client *redis.Client) *Repository {
+func NewRepository(client *redis.Client, opts ...Option) *Repository {
 	r := Repository{
 		client: client,
 	}
+	for _, opt := range opts {
+		opt(&r)
+	}
 
 	return &r
 }
 
 // Repository represnets redis storage.
 type Repository struct {
-	client *redis.Client
+	client  *redis.Client
+	timeout time.Duration
+}
+
+// context returns context of a single operation. Client
+// sets socket deadlines from its deadline and does not
+// send commands when it is done.
+func (r *Repository) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// scanCount is a number of keys requested
+// per one scan iteration.
+const scanCount = 100
+
+// entry is stored representation of cached places.
+type entry struct {
+	Params   search.Params
+	Places   []place.Model
+	CachedAt time.Time
 }
 
 // Cache caches query in storage.
 func (r *Repository) Cache(ctx context.Context, p search.Params, places []place.Model) error {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+
+	e := entry{
+		Params:   p,
+		Places:   places,
+		CachedAt: time.Now(),
+	}
+
 	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(places); err != nil {
+	if err := gob.NewEncoder(&buf).Encode(&e); err != nil {
 		return errors.Wrap(err, "encode gob")
 	}
 
-	key := paramsToHex(p)
-	if err := r.client.Set(key, buf.Bytes(), 0).Err(); err != nil {
+	key := paramsKey(ctx, p)
+	if err := r.client.Set(ctx, key, buf.Bytes(), 0).Err(); err != nil {
 		return errors.Wrap(err, "set key")
 	}
 	return nil
 }
 
 // Retrieve retieves cache from storage
-func (r *Repository) Retrieve(ctx context.Context, p search.Params) ([]place.Model, error) {
-	key := paramsToHex(p)
-	data, err := r.client.Get(key).Result()
+func (r *Repository) Retrieve(ctx context.Context, p search.Params) (search.Entry, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+
+	key := paramsKey(ctx, p)
+	data, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, storage.ErrCacheNotFound
+			return search.Entry{}, storage.ErrCacheNotFound
+		}
+
+		return search.Entry{}, errors.Wrap(err, "get key")
+	}
+
+	return decodeEntry(data)
+}
+
+// List lists all cached entries of the tenant.
+func (r *Repository) List(ctx context.Context) ([]search.Entry, error) {
+	prefix := tenant.Prefix(ctx)
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+
+	var entries []search.Entry
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, prefix+"*", scanCount).Result()
+		if err != nil {
+			return nil, errors.Wrap(err, "scan keys")
+		}
+
+		// Keys of other kinds of entries and of other
+		// tenants have prefixes.
+		places := keys[:0]
+		for _, key := range keys {
+			if !strings.Contains(strings.TrimPrefix(key, prefix), ":") {
+				places = append(places, key)
+			}
 		}
 
-		return nil, errors.Wrap(err, "get key")
+		if len(places) > 0 {
+			values, err := r.client.MGet(ctx, places...).Result()
+			if err != nil {
+				return nil, errors.Wrap(err, "get keys")
+			}
+
+			for i, v := range values {
+				data, ok := v.(string)
+				if !ok {
+					// Key expired or deleted during scan.
+					continue
+				}
+				e, err := decodeEntry(data)
+				if err != nil {
+					// One broken entry must not hide the rest.
+					log.Warn(errors.Wrap(err, "skip entry"), map[string]interface{}{
+						"key": places[i],
+					})
+					continue
+				}
+				entries = append(entries, e)
+			}
+		}
+
+		if next == 0 {
+			return entries, nil
+		}
+		cursor = next
 	}
+}
+
+// Delete deletes cache of query, its negative entry and its
+// places from slug index of the params locale, so they are
+// not served by lookup. Returns storage.ErrCacheNotFound when
+// there were neither places nor negative entry.
+func (r *Repository) Delete(ctx context.Context, p search.Params) error {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
 
-	reader := strings.NewReader(data)
+	key := paramsKey(ctx, p)
 	var places []place.Model
-	if err := gob.NewDecoder(reader).Decode(&places); err != nil {
-		return nil, errors.Wrap(err, "decode gob")
+	data, err := r.client.Get(ctx, key).Result()
+	switch {
+	case err == nil:
+		// Broken entry is deleted without its index.
+		if e, err := decodeEntry(data); err == nil {
+			places = e.Places
+		}
+	case err != redis.Nil:
+		return errors.Wrap(err, "get key")
+	}
+
+	pipe := r.client.TxPipeline()
+	deleted := pipe.Del(ctx, key, tenant.Prefix(ctx)+negativePrefix+paramsToHex(p))
+	for _, m := range places {
+		pipe.HDel(ctx, tenant.Prefix(ctx)+placePrefix+m.Slug, p.Locale)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Wrap(err, "delete keys")
+	}
+	if deleted.Val() == 0 {
+		return storage.ErrCacheNotFound
+	}
+	return nil
+}
+
+func decodeEntry(data string) (search.Entry, error) {
+	var e entry
+	if err := gob.NewDecoder(strings.NewReader(data)).Decode(&e); err != nil {
+		// Entries cached before timestamps were added
+		// contain only places.
+		if err := gob.NewDecoder(strings.NewReader(data)).Decode(&e.Places); err != nil {
+			return search.Entry{}, errors.Wrap(err, "decode gob")
+		}
+	}
+
+	return search.Entry{Params: e.Params, Places: e.Places, CachedAt: e.CachedAt}, nil
+}
+
+// CacheNegative caches negative result of query in storage
+// for a given ttl.
+func (r *Repository) CacheNegative(ctx context.Context, p search.Params, negative search.Negative, ttl time.Duration) error {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+
+	key := tenant.Prefix(ctx) + negativePrefix + paramsToHex(p)
+	if err := r.client.Set(ctx, key, int(negative), ttl).Err(); err != nil {
+		return errors.Wrap(err, "set key")
+	}
+	return nil
+}
+
+// RetrieveNegative retrieves negative cache from storage.
+func (r *Repository) RetrieveNegative(ctx context.Context, p search.Params) (search.Negative, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+
+	key := tenant.Prefix(ctx) + negativePrefix + paramsToHex(p)
+	negative, err := r.client.Get(ctx, key).Int()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, storage.ErrCacheNotFound
+		}
+
+		return 0, errors.Wrap(err, "get key")
+	}
+
+	return search.Negative(negative), nil
+}
+
+// IndexPlaces saves places in slug index, every slug
+// keeps its place for each locale separately.
+func (r *Repository) IndexPlaces(ctx context.Context, locale string, places []place.Model) error {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+
+	if len(places) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for _, m := range places {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+			return errors.Wrap(err, "encode gob")
+		}
+		pipe.HSet(ctx, tenant.Prefix(ctx)+placePrefix+m.Slug, locale, buf.Bytes())
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Wrap(err, "set fields")
+	}
+	return nil
+}
+
+// RetrievePlace retrieves place from slug index.
+func (r *Repository) RetrievePlace(ctx context.Context, slug, locale string) (place.Model, error) {
+	ctx, cancel := r.context(ctx)
+	defer cancel()
+
+	data, err := r.client.HGet(ctx, tenant.Prefix(ctx)+placePrefix+slug, locale).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return place.Model{}, storage.ErrCacheNotFound
+		}
+
+		return place.Model{}, errors.Wrap(err, "get field")
+	}
+
+	var model place.Model
+	if err := gob.NewDecoder(strings.NewReader(data)).Decode(&model); err != nil {
+		return place.Model{}, errors.Wrap(err, "decode gob")
 	}
 
-	return places, nil
+	return model, nil
+}
+
+// paramsKey returns key of params entry, keys
+// of tenants are prefixed with their ids.
+func paramsKey(ctx context.Context, p search.Params) string {
+	return tenant.Prefix(ctx) + paramsToHex(p)
 }
 
 func paramsToHex(p search.Params) string {
@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"go.opencensus.io/trace"
+
+	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/storage/storagetest"
+)
+
+func TestRepository(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Backend {
+		s, client := prepareRedis(t)
+		return storagetest.Backend{
+			Repository: NewRepository(client),
+			Advance:    s.FastForward,
+		}
+	})
+}
+
+func TestRepositoryTimeout(t *testing.T) {
+	// Server accepts connections but never replies.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:        l.Addr().String(),
+		ReadTimeout: time.Minute,
+	})
+	defer client.Close()
+	r := NewRepository(client, WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	if _, err := r.Retrieve(context.Background(), search.Params{Term: "Moscow"}); err == nil {
+		t.Error("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected operation to time out, took: %v", elapsed)
+	}
+}
+
+func TestTracingHook(t *testing.T) {
+	_, client := prepareRedis(t)
+	client.AddHook(TracingHook{})
+	r := NewRepository(client)
+
+	var e exporter
+	trace.RegisterExporter(&e)
+	defer trace.UnregisterExporter(&e)
+
+	ctx, parent := trace.StartSpan(context.Background(), "repository.retrieve", trace.WithSampler(trace.AlwaysSample()))
+	r.Retrieve(ctx, search.Params{Term: "Moscow"})
+	parent.End()
+
+	spans := e.spans()
+	var found bool
+	for _, s := range spans {
+		if s.Name == "redis.get" {
+			found = true
+			if s.ParentSpanID != parent.SpanContext().SpanID {
+				t.Errorf("expected redis span to be child of repository span")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected redis.get span got: %v", spans)
+	}
+}
+
+func prepareRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("run miniredis: %v", err)
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+	t.Cleanup(func() {
+		client.Close()
+		s.Close()
+	})
+
+	return s, client
+}
+
+type exporter struct {
+	mu   sync.Mutex
+	data []*trace.SpanData
+}
+
+func (e *exporter) ExportSpan(s *trace.SpanData) {
+	e.mu.Lock()
+	e.data = append(e.data, s)
+	e.mu.Unlock()
+}
+
+func (e *exporter) spans() []*trace.SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*trace.SpanData{}, e.data...)
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"go.opencensus.io/trace"
+)
+
+// TracingHook traces redis commands, their spans are
+// children of the span carried by command context.
+type TracingHook struct{}
+
+// BeforeProcess starts span of the command.
+func (TracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, span := trace.StartSpan(ctx, "redis."+cmd.Name(), trace.WithSpanKind(trace.SpanKindClient))
+	span.AddAttributes(trace.StringAttribute("redis.command", cmd.Name()))
+	return ctx, nil
+}
+
+// AfterProcess ends span of the command.
+func (TracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endSpan(ctx, cmd.Err())
+	return nil
+}
+
+// BeforeProcessPipeline starts span of the pipeline.
+func (TracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	ctx, span := trace.StartSpan(ctx, "redis.pipeline", trace.WithSpanKind(trace.SpanKindClient))
+	span.AddAttributes(trace.Int64Attribute("redis.commands", int64(len(cmds))))
+	return ctx, nil
+}
+
+// AfterProcessPipeline ends span of the pipeline.
+func (TracingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	endSpan(ctx, err)
+	return nil
+}
+
+func endSpan(ctx context.Context, err error) {
+	span := trace.FromContext(ctx)
+	switch {
+	case err == nil, err == redis.Nil:
+		span.SetStatus(trace.Status{Code: trace.StatusCodeOK})
+	case err == context.DeadlineExceeded:
+		span.SetStatus(trace.Status{Code: trace.StatusCodeDeadlineExceeded, Message: err.Error()})
+	case err == context.Canceled:
+		span.SetStatus(trace.Status{Code: trace.StatusCodeCancelled, Message: err.Error()})
+	default:
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	span.End()
+}
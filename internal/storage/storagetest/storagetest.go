@@ -0,0 +1,507 @@
+// Package storagetest provides conformance tests of
+// search.Repository implementations. Backend package
+// runs them from its own test:
+//
+//	func TestRepository(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storagetest.Backend {
+//			return storagetest.Backend{Repository: ..., Advance: ...}
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/romanyx/places/internal/place"
+	"github.com/romanyx/places/internal/search"
+	"github.com/romanyx/places/internal/storage"
+	"github.com/romanyx/places/internal/tenant"
+)
+
+// Backend is a repository under test.
+type Backend struct {
+	Repository search.Repository
+	// Advance moves clock of the repository forward,
+	// it is used to check expiration.
+	Advance func(time.Duration)
+}
+
+// Factory returns backend with empty repository,
+// it is called for every test.
+type Factory func(t *testing.T) Backend
+
+// Run runs conformance tests against backends
+// produced by factory.
+func Run(t *testing.T, factory Factory) {
+	tt := []struct {
+		name string
+		test func(*testing.T, Backend)
+	}{
+		{name: "cache and retrieve", test: testCacheRetrieve},
+		{name: "retrieve not found", test: testRetrieveNotFound},
+		{name: "negative", test: testNegative},
+		{name: "negative expiration", test: testNegativeExpiration},
+		{name: "index places", test: testIndexPlaces},
+		{name: "list and delete", test: testListDelete},
+		{name: "delete negative and index", test: testDeleteNegativeIndex},
+		{name: "overwrite", test: testOverwrite},
+		{name: "concurrent writers", test: testConcurrentWriters},
+		{name: "large payload", test: testLargePayload},
+		{name: "context cancellation", test: testContextCancellation},
+		{name: "stale after timeout", test: testStaleAfterTimeout},
+		{name: "params equivalence", test: testParamsEquivalence},
+		{name: "tenant isolation", test: testTenantIsolation},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.test(t, factory(t))
+		})
+	}
+}
+
+var (
+	moscow = search.Params{Term: "Moscow", Locale: "en", Types: []string{"city", "airport"}}
+	paris  = search.Params{Term: "Paris", Locale: "en"}
+
+	moscowPlaces = []place.Model{
+		{Slug: "MOW", Title: "Moscow", SubTitle: "Russia", Type: "city", CountryCode: "RU", Weight: 100},
+		{Slug: "SVO", Title: "Sheremetyevo", SubTitle: "Moscow", Type: "airport", CountryCode: "RU", Weight: 50},
+	}
+)
+
+func testCacheRetrieve(t *testing.T, b Backend) {
+	ctx := context.Background()
+	if err := b.Repository.Cache(ctx, moscow, moscowPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+
+	e, err := b.Repository.Retrieve(ctx, moscow)
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if !reflect.DeepEqual(e.Places, moscowPlaces) {
+		t.Errorf("expected places: %v got: %v", moscowPlaces, e.Places)
+	}
+	if !reflect.DeepEqual(e.Params, moscow) {
+		t.Errorf("expected params: %v got: %v", moscow, e.Params)
+	}
+	if e.CachedAt.IsZero() {
+		t.Error("expected cached at to be set")
+	}
+}
+
+func testRetrieveNotFound(t *testing.T, b Backend) {
+	_, err := b.Repository.Retrieve(context.Background(), paris)
+	expectNotFound(t, err)
+}
+
+func testNegative(t *testing.T, b Backend) {
+	ctx := context.Background()
+	_, err := b.Repository.RetrieveNegative(ctx, paris)
+	expectNotFound(t, err)
+
+	if err := b.Repository.CacheNegative(ctx, paris, search.NegativeBadRequest, time.Minute); err != nil {
+		t.Fatalf("cache negative: %v", err)
+	}
+	negative, err := b.Repository.RetrieveNegative(ctx, paris)
+	if err != nil {
+		t.Fatalf("retrieve negative: %v", err)
+	}
+	if negative != search.NegativeBadRequest {
+		t.Errorf("expected negative: %v got: %v", search.NegativeBadRequest, negative)
+	}
+
+	// Negative cache is kept apart from places.
+	_, err = b.Repository.Retrieve(ctx, paris)
+	expectNotFound(t, err)
+}
+
+func testNegativeExpiration(t *testing.T, b Backend) {
+	ctx := context.Background()
+	if err := b.Repository.CacheNegative(ctx, paris, search.NegativeEmpty, time.Minute); err != nil {
+		t.Fatalf("cache negative: %v", err)
+	}
+
+	b.Advance(30 * time.Second)
+	if _, err := b.Repository.RetrieveNegative(ctx, paris); err != nil {
+		t.Fatalf("expected negative before ttl: %v", err)
+	}
+
+	b.Advance(time.Minute)
+	_, err := b.Repository.RetrieveNegative(ctx, paris)
+	expectNotFound(t, err)
+}
+
+func testIndexPlaces(t *testing.T, b Backend) {
+	ctx := context.Background()
+	if err := b.Repository.IndexPlaces(ctx, "en", moscowPlaces); err != nil {
+		t.Fatalf("index places: %v", err)
+	}
+
+	m, err := b.Repository.RetrievePlace(ctx, "SVO", "en")
+	if err != nil {
+		t.Fatalf("retrieve place: %v", err)
+	}
+	if !reflect.DeepEqual(m, moscowPlaces[1]) {
+		t.Errorf("expected place: %v got: %v", moscowPlaces[1], m)
+	}
+
+	_, err = b.Repository.RetrievePlace(ctx, "SVO", "ru")
+	expectNotFound(t, err)
+}
+
+func testListDelete(t *testing.T, b Backend) {
+	ctx := context.Background()
+	if err := b.Repository.Cache(ctx, moscow, moscowPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+	if err := b.Repository.Cache(ctx, paris, []place.Model{{Slug: "PAR"}}); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+	// Negative entries and index are not listed.
+	if err := b.Repository.CacheNegative(ctx, moscow, search.NegativeEmpty, time.Minute); err != nil {
+		t.Fatalf("cache negative: %v", err)
+	}
+	if err := b.Repository.IndexPlaces(ctx, "en", moscowPlaces); err != nil {
+		t.Fatalf("index places: %v", err)
+	}
+
+	entries, err := b.Repository.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries got: %d", len(entries))
+	}
+
+	if err := b.Repository.Delete(ctx, paris); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	_, err = b.Repository.Retrieve(ctx, paris)
+	expectNotFound(t, err)
+	expectNotFound(t, b.Repository.Delete(ctx, paris))
+
+	entries, err = b.Repository.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Params.Term != moscow.Term {
+		t.Errorf("expected only moscow entry got: %v", entries)
+	}
+}
+
+func testDeleteNegativeIndex(t *testing.T, b Backend) {
+	ctx := context.Background()
+	if err := b.Repository.Cache(ctx, moscow, moscowPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+	if err := b.Repository.IndexPlaces(ctx, "en", moscowPlaces); err != nil {
+		t.Fatalf("index places: %v", err)
+	}
+	if err := b.Repository.IndexPlaces(ctx, "ru", moscowPlaces); err != nil {
+		t.Fatalf("index places: %v", err)
+	}
+	if err := b.Repository.CacheNegative(ctx, paris, search.NegativeEmpty, time.Minute); err != nil {
+		t.Fatalf("cache negative: %v", err)
+	}
+
+	if err := b.Repository.Delete(ctx, moscow); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	_, err := b.Repository.RetrievePlace(ctx, "MOW", "en")
+	expectNotFound(t, err)
+	// Index of other locales belongs to other entries.
+	if _, err := b.Repository.RetrievePlace(ctx, "MOW", "ru"); err != nil {
+		t.Errorf("expected place of other locale to stay: %v", err)
+	}
+
+	// Negative entry alone is deleted as well.
+	if err := b.Repository.Delete(ctx, paris); err != nil {
+		t.Fatalf("delete negative: %v", err)
+	}
+	_, err = b.Repository.RetrieveNegative(ctx, paris)
+	expectNotFound(t, err)
+}
+
+func testOverwrite(t *testing.T, b Backend) {
+	ctx := context.Background()
+	if err := b.Repository.Cache(ctx, paris, moscowPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+	parisPlaces := []place.Model{{Slug: "PAR", Title: "Paris"}}
+	if err := b.Repository.Cache(ctx, paris, parisPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+
+	e, err := b.Repository.Retrieve(ctx, paris)
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if !reflect.DeepEqual(e.Places, parisPlaces) {
+		t.Errorf("expected places: %v got: %v", parisPlaces, e.Places)
+	}
+
+	entries, err := b.Repository.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected 1 entry got: %d", len(entries))
+	}
+
+	// Negative cache is replaced along with its ttl.
+	if err := b.Repository.CacheNegative(ctx, paris, search.NegativeEmpty, time.Minute); err != nil {
+		t.Fatalf("cache negative: %v", err)
+	}
+	if err := b.Repository.CacheNegative(ctx, paris, search.NegativeBadRequest, time.Hour); err != nil {
+		t.Fatalf("cache negative: %v", err)
+	}
+	b.Advance(2 * time.Minute)
+	negative, err := b.Repository.RetrieveNegative(ctx, paris)
+	if err != nil {
+		t.Fatalf("retrieve negative: %v", err)
+	}
+	if negative != search.NegativeBadRequest {
+		t.Errorf("expected negative: %v got: %v", search.NegativeBadRequest, negative)
+	}
+}
+
+func testConcurrentWriters(t *testing.T, b Backend) {
+	const writers = 16
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := search.Params{Term: fmt.Sprintf("term-%d", i), Locale: "en"}
+			places := []place.Model{{Slug: fmt.Sprint(i)}}
+			errs <- b.Repository.Cache(ctx, p, places)
+			// All writers race for the same entry as well.
+			errs <- b.Repository.Cache(ctx, paris, places)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("cache: %v", err)
+		}
+	}
+
+	for i := 0; i < writers; i++ {
+		p := search.Params{Term: fmt.Sprintf("term-%d", i), Locale: "en"}
+		e, err := b.Repository.Retrieve(ctx, p)
+		if err != nil {
+			t.Fatalf("retrieve %v: %v", p, err)
+		}
+		if len(e.Places) != 1 || e.Places[0].Slug != fmt.Sprint(i) {
+			t.Errorf("expected place %d got: %v", i, e.Places)
+		}
+	}
+
+	// One of the writers wins, entry must not be corrupted.
+	e, err := b.Repository.Retrieve(ctx, paris)
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if len(e.Places) != 1 {
+		t.Errorf("expected 1 place got: %v", e.Places)
+	}
+}
+
+func testLargePayload(t *testing.T, b Backend) {
+	const size = 10000
+	ctx := context.Background()
+
+	places := make([]place.Model, size)
+	for i := range places {
+		places[i] = place.Model{
+			Slug:     fmt.Sprintf("S%05d", i),
+			Title:    strings.Repeat("Title ", 20),
+			SubTitle: strings.Repeat("Subtitle ", 20),
+			Weight:   i,
+		}
+	}
+
+	if err := b.Repository.Cache(ctx, moscow, places); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+	e, err := b.Repository.Retrieve(ctx, moscow)
+	if err != nil {
+		t.Fatalf("retrieve: %v", err)
+	}
+	if !reflect.DeepEqual(e.Places, places) {
+		t.Errorf("expected %d places to round-trip got: %d", size, len(e.Places))
+	}
+}
+
+func testContextCancellation(t *testing.T, b Backend) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := b.Repository.Cache(ctx, moscow, moscowPlaces)
+	expectCanceled(t, err)
+	_, err = b.Repository.Retrieve(ctx, moscow)
+	expectCanceled(t, err)
+	err = b.Repository.CacheNegative(ctx, moscow, search.NegativeEmpty, time.Minute)
+	expectCanceled(t, err)
+	_, err = b.Repository.RetrieveNegative(ctx, moscow)
+	expectCanceled(t, err)
+	err = b.Repository.IndexPlaces(ctx, "en", moscowPlaces)
+	expectCanceled(t, err)
+	_, err = b.Repository.RetrievePlace(ctx, "MOW", "en")
+	expectCanceled(t, err)
+	_, err = b.Repository.List(ctx)
+	expectCanceled(t, err)
+	err = b.Repository.Delete(ctx, moscow)
+	expectCanceled(t, err)
+
+	// Nothing is written with cancelled context.
+	_, err = b.Repository.Retrieve(context.Background(), moscow)
+	expectNotFound(t, err)
+	_, err = b.Repository.RetrievePlace(context.Background(), "MOW", "en")
+	expectNotFound(t, err)
+}
+
+// testStaleAfterTimeout checks that service answers from cache
+// when request exceeds deadline of the search.
+func testStaleAfterTimeout(t *testing.T, b Backend) {
+	if err := b.Repository.Cache(context.Background(), moscow, moscowPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+
+	s := search.NewService(requesterFunc(func(ctx context.Context, p search.Params) ([]place.Model, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}), b.Repository, 50*time.Millisecond)
+	ctx, meta := search.WithMeta(context.Background())
+	got, err := s.Search(ctx, moscow)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if !reflect.DeepEqual(moscowPlaces, got) {
+		t.Errorf("expected: %v got: %v", moscowPlaces, got)
+	}
+	if !meta.Stale {
+		t.Error("expected stale result")
+	}
+}
+
+type requesterFunc func(context.Context, search.Params) ([]place.Model, error)
+
+func (f requesterFunc) Request(ctx context.Context, p search.Params) ([]place.Model, error) {
+	return f(ctx, p)
+}
+
+func testParamsEquivalence(t *testing.T, b Backend) {
+	ctx := context.Background()
+	p := search.Params{Term: "Moscow", Locale: "en", Types: []string{"city"}}
+	if err := b.Repository.Cache(ctx, p, moscowPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+
+	// Equal params with separately allocated types find the entry.
+	equal := search.Params{Term: "Moscow", Locale: "en", Types: append([]string{}, p.Types...)}
+	if _, err := b.Repository.Retrieve(ctx, equal); err != nil {
+		t.Errorf("expected entry for equal params: %v", err)
+	}
+
+	// Params which differ in any field are distinct entries.
+	for _, other := range []search.Params{
+		{Term: "moscow", Locale: "en", Types: []string{"city"}},
+		{Term: "Moscow", Locale: "ru", Types: []string{"city"}},
+		{Term: "Moscow", Locale: "en", Types: []string{"airport"}},
+		{Term: "Moscow", Locale: "en", Types: []string{"city", "airport"}},
+		{Term: "Moscow", Locale: "en"},
+	} {
+		_, err := b.Repository.Retrieve(ctx, other)
+		expectNotFound(t, err)
+	}
+
+	// Nil and empty types are equivalent.
+	empty := search.Params{Term: "Paris", Locale: "en", Types: []string{}}
+	if err := b.Repository.Cache(ctx, empty, moscowPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+	if _, err := b.Repository.Retrieve(ctx, paris); err != nil {
+		t.Errorf("expected entry for params with nil types: %v", err)
+	}
+}
+
+func testTenantIsolation(t *testing.T, b Backend) {
+	ctx := context.Background()
+	acme := tenant.NewContext(ctx, &tenant.Tenant{ID: "acme"})
+	if err := b.Repository.Cache(acme, moscow, moscowPlaces); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+	if err := b.Repository.CacheNegative(acme, paris, search.NegativeEmpty, time.Minute); err != nil {
+		t.Fatalf("cache negative: %v", err)
+	}
+	if err := b.Repository.IndexPlaces(acme, "en", moscowPlaces); err != nil {
+		t.Fatalf("index places: %v", err)
+	}
+	if err := b.Repository.Cache(ctx, paris, []place.Model{{Slug: "PAR"}}); err != nil {
+		t.Fatalf("cache: %v", err)
+	}
+
+	// Default tenant does not see entries of acme.
+	_, err := b.Repository.Retrieve(ctx, moscow)
+	expectNotFound(t, err)
+	_, err = b.Repository.RetrieveNegative(ctx, paris)
+	expectNotFound(t, err)
+	_, err = b.Repository.RetrievePlace(ctx, "MOW", "en")
+	expectNotFound(t, err)
+
+	// And acme does not see entries of default tenant.
+	_, err = b.Repository.Retrieve(acme, paris)
+	expectNotFound(t, err)
+
+	entries, err := b.Repository.List(acme)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Params.Term != moscow.Term {
+		t.Errorf("expected only moscow entry got: %v", entries)
+	}
+	entries, err = b.Repository.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Params.Term != paris.Term {
+		t.Errorf("expected only paris entry got: %v", entries)
+	}
+
+	expectNotFound(t, b.Repository.Delete(ctx, moscow))
+	if err := b.Repository.Delete(acme, moscow); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+}
+
+func expectCanceled(t *testing.T, err error) {
+	t.Helper()
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("expected: %v got: %v", context.Canceled, err)
+	}
+}
+
+func expectNotFound(t *testing.T, err error) {
+	t.Helper()
+	if errors.Cause(err) != storage.ErrCacheNotFound {
+		t.Errorf("expected: %v got: %v", storage.ErrCacheNotFound, err)
+	}
+}
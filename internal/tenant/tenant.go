@@ -0,0 +1,155 @@
+// Package tenant describes brands served by one deployment,
+// each tenant has own aviasales params, locale default,
+// request limit and cache namespace.
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/tag"
+)
+
+// KeyPrefix starts storage keys of all tenants
+// except the default one.
+const KeyPrefix = "tenant:"
+
+// Key tags metrics with tenant id.
+var Key = mustKey("tenant")
+
+// validID restricts ids to characters which are
+// safe in storage keys and key patterns.
+var validID = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Tenant configures a brand served by deployment.
+type Tenant struct {
+	// ID identifies tenant, cache keys of the
+	// tenant are prefixed with it.
+	ID string `json:"id"`
+	// Hosts contains request hosts of the tenant.
+	Hosts []string `json:"hosts"`
+	// Marker is a partner marker sent to aviasales.
+	Marker string `json:"marker"`
+	// Params contains other query params sent to aviasales.
+	Params map[string]string `json:"params"`
+	// Locale is used when request has neither
+	// locale nor Accept-Language header.
+	Locale string `json:"locale"`
+	// Limit is a maximum number of concurrent aviasales
+	// requests of the tenant, zero keeps the default.
+	Limit int `json:"limit"`
+}
+
+// Registry finds tenants by id and host.
+type Registry struct {
+	tenants []*Tenant
+	byID    map[string]*Tenant
+	byHost  map[string]*Tenant
+}
+
+// NewRegistry validates tenants and initialize registry.
+func NewRegistry(tenants []Tenant) (*Registry, error) {
+	r := Registry{
+		byID:   make(map[string]*Tenant, len(tenants)),
+		byHost: make(map[string]*Tenant),
+	}
+
+	for i := range tenants {
+		t := &tenants[i]
+		if !validID.MatchString(t.ID) {
+			return nil, errors.Errorf("invalid tenant id: %q", t.ID)
+		}
+		if _, ok := r.byID[t.ID]; ok {
+			return nil, errors.Errorf("duplicate tenant id: %s", t.ID)
+		}
+		r.byID[t.ID] = t
+
+		for _, host := range t.Hosts {
+			host = strings.ToLower(host)
+			if _, ok := r.byHost[host]; ok {
+				return nil, errors.Errorf("duplicate tenant host: %s", host)
+			}
+			r.byHost[host] = t
+		}
+		r.tenants = append(r.tenants, t)
+	}
+
+	return &r, nil
+}
+
+// Load reads JSON array of tenants from file.
+func Load(path string) (*Registry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open tenants")
+	}
+	defer f.Close()
+
+	var tenants []Tenant
+	if err := json.NewDecoder(f).Decode(&tenants); err != nil {
+		return nil, errors.Wrap(err, "decode tenants")
+	}
+
+	return NewRegistry(tenants)
+}
+
+// Tenants returns all tenants.
+func (r *Registry) Tenants() []*Tenant {
+	return r.tenants
+}
+
+// ByID finds tenant by id.
+func (r *Registry) ByID(id string) (*Tenant, bool) {
+	t, ok := r.byID[id]
+	return t, ok
+}
+
+// ByHost finds tenant by request host, port is ignored.
+func (r *Registry) ByHost(host string) (*Tenant, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	t, ok := r.byHost[strings.ToLower(host)]
+	return t, ok
+}
+
+type tenantKey struct{}
+
+// NewContext returns copy of ctx which carries tenant,
+// metrics recorded with it are tagged with tenant id.
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	ctx = context.WithValue(ctx, tenantKey{}, t)
+	if tagged, err := tag.New(ctx, tag.Upsert(Key, t.ID)); err == nil {
+		ctx = tagged
+	}
+	return ctx
+}
+
+// FromContext returns tenant stored in ctx, or nil when
+// request is served for the default tenant.
+func FromContext(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(tenantKey{}).(*Tenant)
+	return t
+}
+
+// Prefix returns prefix of storage keys of the tenant
+// stored in ctx, default tenant has no prefix.
+func Prefix(ctx context.Context) string {
+	if t := FromContext(ctx); t != nil {
+		return KeyPrefix + t.ID + ":"
+	}
+	return ""
+}
+
+func mustKey(name string) tag.Key {
+	k, err := tag.NewKey(name)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
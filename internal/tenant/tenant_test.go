@@ -0,0 +1,88 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewRegistry(t *testing.T) {
+	tt := []struct {
+		name      string
+		tenants   []Tenant
+		expectErr bool
+	}{
+		{
+			name:    "valid",
+			tenants: []Tenant{{ID: "acme", Hosts: []string{"acme.example.com"}}, {ID: "globex"}},
+		},
+		{
+			name:      "invalid id",
+			tenants:   []Tenant{{ID: "acme:*"}},
+			expectErr: true,
+		},
+		{
+			name:      "empty id",
+			tenants:   []Tenant{{}},
+			expectErr: true,
+		},
+		{
+			name:      "duplicate id",
+			tenants:   []Tenant{{ID: "acme"}, {ID: "acme"}},
+			expectErr: true,
+		},
+		{
+			name:      "duplicate host",
+			tenants:   []Tenant{{ID: "acme", Hosts: []string{"example.com"}}, {ID: "globex", Hosts: []string{"EXAMPLE.com"}}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewRegistry(tc.tenants)
+			if (err != nil) != tc.expectErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRegistryByHost(t *testing.T) {
+	r, err := NewRegistry([]Tenant{{ID: "acme", Hosts: []string{"acme.example.com"}}})
+	if err != nil {
+		t.Fatalf("new registry: %v", err)
+	}
+
+	tt := []struct {
+		host   string
+		expect bool
+	}{
+		{host: "acme.example.com", expect: true},
+		{host: "ACME.example.com:8080", expect: true},
+		{host: "example.com"},
+	}
+
+	for _, tc := range tt {
+		if _, ok := r.ByHost(tc.host); ok != tc.expect {
+			t.Errorf("host %s expected: %v got: %v", tc.host, tc.expect, ok)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	ctx := context.Background()
+	if got := Prefix(ctx); got != "" {
+		t.Errorf("expected no prefix got: %q", got)
+	}
+
+	ctx = NewContext(ctx, &Tenant{ID: "acme"})
+	if got := Prefix(ctx); got != "tenant:acme:" {
+		t.Errorf("expected: %q got: %q", "tenant:acme:", got)
+	}
+	if got := FromContext(ctx); got == nil || got.ID != "acme" {
+		t.Errorf("expected acme tenant got: %v", got)
+	}
+}